@@ -0,0 +1,234 @@
+package adjudication
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// Config 分歧判定配置
+type Config struct {
+	Threshold  float64  `json:"threshold"`  // 评分最大差值达到该值即视为分歧
+	Dimensions []string `json:"dimensions"` // 参与判定的维度：score、consistency、sufficiency
+}
+
+// DefaultConfig 默认配置：score相差2分及以上
+var DefaultConfig = Config{Threshold: 2, Dimensions: []string{analytics.DimensionScore}}
+
+// LoadConfig 从环境变量读取配置：ADJUDICATION_THRESHOLD、ADJUDICATION_DIMENSIONS（逗号分隔）
+func LoadConfig() Config {
+	cfg := Config{Threshold: DefaultConfig.Threshold, Dimensions: DefaultConfig.Dimensions}
+
+	if value := os.Getenv("ADJUDICATION_THRESHOLD"); value != "" {
+		if threshold, err := strconv.ParseFloat(value, 64); err == nil && threshold > 0 {
+			cfg.Threshold = threshold
+		} else {
+			log.Printf("Warning: invalid ADJUDICATION_THRESHOLD %q, using %g", value, cfg.Threshold)
+		}
+	}
+	if value := os.Getenv("ADJUDICATION_DIMENSIONS"); value != "" {
+		dimensions, err := ParseDimensions(value)
+		if err == nil {
+			cfg.Dimensions = dimensions
+		} else {
+			log.Printf("Warning: %v, using %s", err, strings.Join(cfg.Dimensions, ","))
+		}
+	}
+	return cfg
+}
+
+// ParseDimensions 解析逗号分隔的维度列表
+func ParseDimensions(value string) ([]string, error) {
+	var dimensions []string
+	for _, dimension := range strings.Split(value, ",") {
+		dimension = strings.TrimSpace(dimension)
+		if dimension == "" {
+			continue
+		}
+		if !isDimension(dimension) {
+			return nil, fmt.Errorf("invalid adjudication dimension %q", dimension)
+		}
+		dimensions = append(dimensions, dimension)
+	}
+	if len(dimensions) == 0 {
+		return nil, fmt.Errorf("no adjudication dimensions")
+	}
+	return dimensions, nil
+}
+
+func isDimension(dimension string) bool {
+	for _, d := range analytics.Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// Disagreement 某医生某trait上超过阈值的分歧
+type Disagreement struct {
+	PhysicianID int
+	Trait       string
+	Spread      float64   // 各维度中评分最大差值的最大值
+	Evaluators  []string  // 参与的评分者
+	LatestAt    time.Time // 最近一次标注时间
+}
+
+// rating 评分者完成回顾后的最终人类标注
+type rating struct {
+	analytics.HumanRating
+	Timestamp time.Time
+}
+
+// FindDisagreements 查找评分者之间超过阈值的分歧。只统计已完成回顾的标注，不含注意力检查任务，
+// 同一评分者有多条时取最新一条。physicianID为0、trait为空时不筛选
+func FindDisagreements(q db.Querier, cfg Config, physicianID int, trait string) ([]Disagreement, error) {
+	rows, err := q.Query(`
+		SELECT DISTINCT ON (h.physician_id, h.trait, h.evaluator)
+			h.physician_id, h.evaluator, h.trait, h.score, h.consistency, h.sufficiency, h.timestamp
+		FROM human_annotations h
+		JOIN trait_progress p
+		  ON p.physician_id = h.physician_id AND p.task_id = h.task_id
+		 AND p.evaluator = h.evaluator AND p.trait = h.trait
+		WHERE p.review_completed = true
+		AND ($1 = 0 OR h.physician_id = $1)
+		AND ($2 = '' OR h.trait = $2)
+		AND `+analytics.NotAttentionCheck+`
+		ORDER BY h.physician_id, h.trait, h.evaluator, h.timestamp DESC
+	`, physicianID, trait)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type itemKey struct {
+		physicianID int
+		trait       string
+	}
+	items := map[itemKey][]rating{}
+	var order []itemKey
+	for rows.Next() {
+		var r rating
+		err := rows.Scan(&r.PhysicianID, &r.Evaluator, &r.Trait, &r.Score, &r.Consistency, &r.Sufficiency, &r.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		key := itemKey{r.PhysicianID, r.Trait}
+		if _, ok := items[key]; !ok {
+			order = append(order, key)
+		}
+		items[key] = append(items[key], r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var disagreements []Disagreement
+	for _, key := range order {
+		ratings := items[key]
+		if len(ratings) < 2 {
+			continue
+		}
+
+		d := Disagreement{PhysicianID: key.physicianID, Trait: key.trait}
+		for _, dimension := range cfg.Dimensions {
+			low, high := ratings[0].Value(dimension), ratings[0].Value(dimension)
+			for _, r := range ratings[1:] {
+				if v := r.Value(dimension); v < low {
+					low = v
+				} else if v > high {
+					high = v
+				}
+			}
+			if spread := float64(high - low); spread > d.Spread {
+				d.Spread = spread
+			}
+		}
+		if d.Spread < cfg.Threshold {
+			continue
+		}
+
+		for _, r := range ratings {
+			d.Evaluators = append(d.Evaluators, r.Evaluator)
+			if r.Timestamp.After(d.LatestAt) {
+				d.LatestAt = r.Timestamp
+			}
+		}
+		sort.Strings(d.Evaluators)
+		disagreements = append(disagreements, d)
+	}
+	return disagreements, nil
+}
+
+// Detect 查找分歧并创建仲裁任务，返回新建的任务数。
+// 已有未完成仲裁、或上次仲裁之后没有新标注的分歧跳过
+func Detect(q db.Querier, cfg Config, physicianID int, trait string) (int, error) {
+	disagreements, err := FindDisagreements(q, cfg, physicianID, trait)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, d := range disagreements {
+		var open bool
+		var lastCreated sql.NullTime
+		err := q.QueryRow(`
+			SELECT COALESCE(bool_or(status = $3), false), MAX(created_at)
+			FROM adjudication_tasks
+			WHERE physician_id = $1 AND trait = $2 AND status != $4
+		`, d.PhysicianID, d.Trait, models.AdjudicationOpen, models.AdjudicationCancelled).Scan(&open, &lastCreated)
+		if err != nil {
+			return created, err
+		}
+		if open || (lastCreated.Valid && !d.LatestAt.After(lastCreated.Time)) {
+			continue
+		}
+
+		assignee, err := pickAdjudicator(q, d.Evaluators)
+		if err != nil {
+			return created, err
+		}
+
+		result, err := q.Exec(`
+			INSERT INTO adjudication_tasks (physician_id, trait, status, assigned_to, spread, threshold, created_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			ON CONFLICT (physician_id, trait) WHERE status = 'open' DO NOTHING
+		`, d.PhysicianID, d.Trait, models.AdjudicationOpen, assignee, d.Spread, cfg.Threshold, time.Now())
+		if err != nil {
+			return created, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// pickAdjudicator 选择未完成仲裁最少的adjudicator，不选参与了该分歧的评分者；
+// 没有可用的adjudicator时返回空，任务留在待领取池中
+func pickAdjudicator(q db.Querier, exclude []string) (string, error) {
+	var username string
+	err := q.QueryRow(`
+		SELECT u.username
+		FROM users u
+		LEFT JOIN adjudication_tasks a ON a.assigned_to = u.username AND a.status = $1
+		WHERE u.role = $2 AND NOT (u.username = ANY($3))
+		GROUP BY u.username
+		ORDER BY COUNT(a.id), u.username
+		LIMIT 1
+	`, models.AdjudicationOpen, models.RoleAdjudicator, pq.Array(exclude)).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return username, err
+}
@@ -0,0 +1,192 @@
+package analytics
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/stats"
+)
+
+// 参与一致性分析的人类标注维度
+const (
+	DimensionScore       = "score"
+	DimensionConsistency = "consistency"
+	DimensionSufficiency = "sufficiency"
+)
+
+// Dimensions 全部标注维度
+var Dimensions = []string{DimensionScore, DimensionConsistency, DimensionSufficiency}
+
+// HumanRating 每位评分者对某医生某trait的最新人类标注
+type HumanRating struct {
+	PhysicianID int
+	Evaluator   string
+	Trait       string
+	Score       int
+	Consistency int
+	Sufficiency int
+}
+
+// Value 返回指定维度的评分
+func (r HumanRating) Value(dimension string) int {
+	switch dimension {
+	case DimensionConsistency:
+		return r.Consistency
+	case DimensionSufficiency:
+		return r.Sufficiency
+	default:
+		return r.Score
+	}
+}
+
+// NotAttentionCheck 排除注意力检查任务上人类标注h的查询条件。检查任务的标注用于考核评分者，
+// 不计入一致性、共识和分歧检测
+const NotAttentionCheck = `NOT EXISTS (
+	SELECT 1 FROM attention_checks ac WHERE ac.task_id = h.task_id AND ac.physician_id = h.physician_id
+)`
+
+// LoadHumanRatings 读取符合筛选条件的人类标注，不含注意力检查任务。
+// 同一评分者对同一医生同一trait有多条标注（不同任务）时取最新一条。
+func LoadHumanRatings(conn *sql.DB, filter Filter) ([]HumanRating, error) {
+	rows, err := conn.Query(`
+		SELECT DISTINCT ON (h.physician_id, h.trait, h.evaluator)
+			h.physician_id, h.evaluator, h.trait, h.score, h.consistency, h.sufficiency
+		FROM human_annotations h
+		JOIN physicians p ON p.id = h.physician_id
+		WHERE (COALESCE(array_length($1::text[], 1), 0) = 0 OR h.evaluator = ANY($1))
+		AND ($2 = '' OR p.specialty = $2)
+		AND ($3::timestamp IS NULL OR h.timestamp >= $3)
+		AND ($4::timestamp IS NULL OR h.timestamp < $4)
+		AND `+NotAttentionCheck+`
+		ORDER BY h.physician_id, h.trait, h.evaluator, h.timestamp DESC
+	`, pq.Array(filter.Evaluators), filter.Specialty, nullableTime(filter.From), nullableTime(filter.To))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []HumanRating{}
+	for rows.Next() {
+		var r HumanRating
+		err := rows.Scan(&r.PhysicianID, &r.Evaluator, &r.Trait, &r.Score, &r.Consistency, &r.Sufficiency)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+// UnitsByTrait 按trait和维度将评分整理为以医生为条目的评分矩阵
+func UnitsByTrait(ratings []HumanRating, dimension string) map[string][]stats.Unit {
+	byTrait := map[string]map[int]stats.Unit{}
+	for _, r := range ratings {
+		if byTrait[r.Trait] == nil {
+			byTrait[r.Trait] = map[int]stats.Unit{}
+		}
+		if byTrait[r.Trait][r.PhysicianID] == nil {
+			byTrait[r.Trait][r.PhysicianID] = stats.Unit{}
+		}
+		byTrait[r.Trait][r.PhysicianID][r.Evaluator] = r.Value(dimension)
+	}
+
+	result := map[string][]stats.Unit{}
+	for trait, physicians := range byTrait {
+		ids := make([]int, 0, len(physicians))
+		for id := range physicians {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			result[trait] = append(result[trait], physicians[id])
+		}
+	}
+	return result
+}
+
+// DimensionAgreement 某trait某维度上的评分者间一致性
+type DimensionAgreement struct {
+	Units             int                `json:"units"`
+	Ratings           int                `json:"ratings"`
+	PercentAgreement  *float64           `json:"percent_agreement"`
+	CohenKappa        *float64           `json:"cohen_kappa"`
+	FleissKappa       *float64           `json:"fleiss_kappa"`
+	KrippendorffAlpha *float64           `json:"krippendorff_alpha_ordinal"`
+	Pairwise          []stats.PairResult `json:"pairwise"`
+}
+
+// TraitAgreement 某trait各维度的一致性
+type TraitAgreement struct {
+	Trait      string                        `json:"trait"`
+	Dimensions map[string]DimensionAgreement `json:"dimensions"`
+}
+
+// AgreementReport 评分者间一致性报告
+type AgreementReport struct {
+	Filter     Filter           `json:"filter"`
+	Evaluators []string         `json:"evaluators"`
+	Traits     []TraitAgreement `json:"traits"`
+}
+
+// ComputeDimensionAgreement 计算一组条目的全部一致性指标。
+// cohen_kappa为各评分者对Cohen's kappa的平均值。
+func ComputeDimensionAgreement(units []stats.Unit) DimensionAgreement {
+	pairable := stats.PairableUnits(units)
+	ratings := 0
+	for _, unit := range pairable {
+		ratings += len(unit)
+	}
+
+	pairs := stats.PairwiseCohen(pairable)
+	return DimensionAgreement{
+		Units:             len(pairable),
+		Ratings:           ratings,
+		PercentAgreement:  stats.Float(stats.PercentAgreement(pairable)),
+		CohenKappa:        stats.Float(stats.MeanPairwiseCohen(pairs)),
+		FleissKappa:       stats.Float(stats.FleissKappa(pairable)),
+		KrippendorffAlpha: stats.Float(stats.KrippendorffAlphaOrdinal(pairable)),
+		Pairwise:          pairs,
+	}
+}
+
+// InterAnnotatorAgreement 计算每个trait在score、consistency、sufficiency上的评分者间一致性
+func InterAnnotatorAgreement(conn *sql.DB, filter Filter) (*AgreementReport, error) {
+	ratings, err := LoadHumanRatings(conn, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluatorSet := map[string]bool{}
+	for _, r := range ratings {
+		evaluatorSet[r.Evaluator] = true
+	}
+	evaluators := make([]string, 0, len(evaluatorSet))
+	for evaluator := range evaluatorSet {
+		evaluators = append(evaluators, evaluator)
+	}
+	sort.Strings(evaluators)
+
+	unitsByDimension := map[string]map[string][]stats.Unit{}
+	for _, dimension := range Dimensions {
+		unitsByDimension[dimension] = UnitsByTrait(ratings, dimension)
+	}
+
+	report := &AgreementReport{
+		Filter:     filter,
+		Evaluators: evaluators,
+		Traits:     []TraitAgreement{},
+	}
+	for _, trait := range models.AllTraits {
+		traitAgreement := TraitAgreement{
+			Trait:      trait,
+			Dimensions: map[string]DimensionAgreement{},
+		}
+		for _, dimension := range Dimensions {
+			traitAgreement.Dimensions[dimension] = ComputeDimensionAgreement(unitsByDimension[dimension][trait])
+		}
+		report.Traits = append(report.Traits, traitAgreement)
+	}
+	return report, nil
+}
@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Filter 分析数据的筛选条件，零值表示不筛选
+type Filter struct {
+	Evaluators []string  `json:"evaluators,omitempty"`
+	Specialty  string    `json:"specialty,omitempty"`
+	FromDate   string    `json:"from,omitempty"`
+	ToDate     string    `json:"to,omitempty"`
+	From       time.Time `json:"-"` // 包含
+	To         time.Time `json:"-"` // 不包含
+}
+
+// ParseFilter 从字符串参数构建筛选条件，evaluators以逗号分隔，日期格式为YYYY-MM-DD
+func ParseFilter(evaluators, specialty, from, to string) (Filter, error) {
+	filter := Filter{Specialty: strings.TrimSpace(specialty), FromDate: from, ToDate: to}
+
+	for _, evaluator := range strings.Split(evaluators, ",") {
+		if evaluator = strings.TrimSpace(evaluator); evaluator != "" {
+			filter.Evaluators = append(filter.Evaluators, evaluator)
+		}
+	}
+
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date %q: %w", from, err)
+		}
+		filter.From = t
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date %q: %w", to, err)
+		}
+		// 包含截止日期当天
+		filter.To = t.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// nullableTime 零值时间转换为SQL NULL
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
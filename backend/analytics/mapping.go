@@ -0,0 +1,65 @@
+package analytics
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// LabelMapping 模型文本标签到人类1-5评分尺度的映射，Value为nil表示不参与比较（如No Evidence）
+type LabelMapping struct {
+	Dimension string   `json:"dimension"`
+	Label     string   `json:"label"`
+	Value     *float64 `json:"value"`
+}
+
+// LabelMap 维度 -> 规范化标签 -> 映射结果
+type LabelMap map[string]map[string]*float64
+
+// normalizeLabel 标签比较时忽略大小写和首尾空白
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// Lookup 查找标签的映射值；known为false表示映射表中没有该标签
+func (m LabelMap) Lookup(dimension, label string) (value *float64, known bool) {
+	value, known = m[dimension][normalizeLabel(label)]
+	return value, known
+}
+
+// LoadLabelMappings 读取score_label_mappings映射表
+func LoadLabelMappings(conn *sql.DB) ([]LabelMapping, error) {
+	rows, err := conn.Query(`
+		SELECT dimension, label, value FROM score_label_mappings
+		ORDER BY dimension, value NULLS LAST, label
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := []LabelMapping{}
+	for rows.Next() {
+		var mapping LabelMapping
+		var value sql.NullFloat64
+		if err := rows.Scan(&mapping.Dimension, &mapping.Label, &value); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			mapping.Value = &value.Float64
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, rows.Err()
+}
+
+// BuildLabelMap 将映射列表转换为查找表
+func BuildLabelMap(mappings []LabelMapping) LabelMap {
+	m := LabelMap{}
+	for _, mapping := range mappings {
+		if m[mapping.Dimension] == nil {
+			m[mapping.Dimension] = map[string]*float64{}
+		}
+		m[mapping.Dimension][normalizeLabel(mapping.Label)] = mapping.Value
+	}
+	return m
+}
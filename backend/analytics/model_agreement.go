@@ -0,0 +1,316 @@
+package analytics
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/scores"
+	"github.com/phyreview_annotator/stats"
+)
+
+// ModelRating 模型对某医生某trait的原始标注及导入时解析出的有序值（未解析时为nil）
+type ModelRating struct {
+	PhysicianID       int
+	ModelName         string
+	Trait             string
+	Score             string
+	Consistency       string
+	Sufficiency       string
+	ScoreParsed       *models.NormalizedScore
+	ConsistencyParsed *models.NormalizedScore
+	SufficiencyParsed *models.NormalizedScore
+}
+
+// Label 返回指定维度的原始标签
+func (r ModelRating) Label(dimension string) string {
+	switch dimension {
+	case DimensionConsistency:
+		return r.Consistency
+	case DimensionSufficiency:
+		return r.Sufficiency
+	default:
+		return r.Score
+	}
+}
+
+// Parsed 返回指定维度解析出的有序值
+func (r ModelRating) Parsed(dimension string) *models.NormalizedScore {
+	switch dimension {
+	case DimensionConsistency:
+		return r.ConsistencyParsed
+	case DimensionSufficiency:
+		return r.SufficiencyParsed
+	default:
+		return r.ScoreParsed
+	}
+}
+
+// LoadModelRatings 读取模型标注，按医生专科筛选
+func LoadModelRatings(conn *sql.DB, filter Filter) ([]ModelRating, error) {
+	rows, err := conn.Query(`
+		SELECT m.physician_id, m.model_name, LOWER(m.trait),
+			COALESCE(m.score, ''), COALESCE(m.consistency, ''), COALESCE(m.sufficiency, ''),
+			m.score_low, m.score_high, m.score_no_evidence,
+			m.consistency_low, m.consistency_high, m.consistency_no_evidence,
+			m.sufficiency_low, m.sufficiency_high, m.sufficiency_no_evidence
+		FROM model_annotations m
+		JOIN physicians p ON p.id = m.physician_id
+		WHERE ($1 = '' OR p.specialty = $1)
+		ORDER BY m.model_name, m.physician_id
+	`, filter.Specialty)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []ModelRating{}
+	for rows.Next() {
+		var r ModelRating
+		var low, high [3]sql.NullFloat64
+		var noEvidence [3]sql.NullBool
+		err := rows.Scan(&r.PhysicianID, &r.ModelName, &r.Trait, &r.Score, &r.Consistency, &r.Sufficiency,
+			&low[0], &high[0], &noEvidence[0], &low[1], &high[1], &noEvidence[1], &low[2], &high[2], &noEvidence[2])
+		if err != nil {
+			return nil, err
+		}
+		r.ScoreParsed = scores.FromColumns(low[0], high[0], noEvidence[0])
+		r.ConsistencyParsed = scores.FromColumns(low[1], high[1], noEvidence[1])
+		r.SufficiencyParsed = scores.FromColumns(low[2], high[2], noEvidence[2])
+		ratings = append(ratings, r)
+	}
+	return ratings, rows.Err()
+}
+
+// consensusKey 人类共识的索引
+type consensusKey struct {
+	PhysicianID int
+	Trait       string
+	Dimension   string
+}
+
+// MedianConsensus 以各评分者评分的中位数作为人类共识
+func MedianConsensus(ratings []HumanRating) map[consensusKey]float64 {
+	values := map[consensusKey][]float64{}
+	for _, r := range ratings {
+		for _, dimension := range Dimensions {
+			key := consensusKey{r.PhysicianID, r.Trait, dimension}
+			values[key] = append(values[key], float64(r.Value(dimension)))
+		}
+	}
+
+	consensus := map[consensusKey]float64{}
+	for key, v := range values {
+		if median, ok := stats.Median(v); ok {
+			consensus[key] = median
+		}
+	}
+	return consensus
+}
+
+// ModelAgreement 某模型在某trait某维度上与人类共识的一致性
+type ModelAgreement struct {
+	Model          string                    `json:"model"`
+	Trait          string                    `json:"trait"`
+	Dimension      string                    `json:"dimension"`
+	Units          int                       `json:"units"`
+	Excluded       int                       `json:"excluded"`
+	UnknownLabels  []string                  `json:"unknown_labels"`
+	ExactAgreement *float64                  `json:"exact_agreement"`
+	WithinOne      *float64                  `json:"within_one"`
+	MAE            *float64                  `json:"mae"`
+	Spearman       *float64                  `json:"spearman"`
+	Confusion      map[string]map[string]int `json:"confusion_matrix"`
+}
+
+// ModelAgreementReport 人类与模型一致性报告
+type ModelAgreementReport struct {
+	Filter   Filter           `json:"filter"`
+	Mappings []LabelMapping   `json:"mappings"`
+	Results  []ModelAgreement `json:"results"`
+}
+
+// modelValue 模型标签在人类评分尺度上的值：优先使用导入时解析出的有序值（与scores.Parse同一套词汇），
+// 解析器不认识的标签再查映射表，映射值视为Low和High相同的单点。
+// nil表示不参与比较（如No Evidence），known为false表示两者都无法识别
+func modelValue(parsed *models.NormalizedScore, labelMap LabelMap, dimension, label string) (score *models.NormalizedScore, known bool) {
+	if parsed != nil {
+		if parsed.NoEvidence {
+			return nil, true
+		}
+		return parsed, true
+	}
+	value, known := labelMap.Lookup(dimension, label)
+	if value == nil {
+		return nil, known
+	}
+	return &models.NormalizedScore{Low: *value, High: *value, Value: *value}, true
+}
+
+// rangeDistance 人类共识到模型评分区间[Low, High]的距离，落在区间内为0。
+// 区间标签（如Low to Moderate）只要覆盖共识即算完全一致，不按中点取整
+func rangeDistance(score models.NormalizedScore, human float64) float64 {
+	switch {
+	case human < score.Low:
+		return score.Low - human
+	case human > score.High:
+		return human - score.High
+	}
+	return 0
+}
+
+// HumanModelAgreement 将模型标签转换到人类评分尺度（见modelValue），
+// 与人类共识（各评分者中位数）比较，计算每个模型、trait、维度的一致率、MAE、
+// 斯皮尔曼相关和混淆矩阵（行：模型原始标签，列：人类共识）。
+// 无证据的标签计入excluded，无法识别的标签计入unknown_labels。
+func HumanModelAgreement(conn *sql.DB, filter Filter) (*ModelAgreementReport, error) {
+	mappings, err := LoadLabelMappings(conn)
+	if err != nil {
+		return nil, err
+	}
+	labelMap := BuildLabelMap(mappings)
+
+	humanRatings, err := LoadHumanRatings(conn, filter)
+	if err != nil {
+		return nil, err
+	}
+	consensus := MedianConsensus(humanRatings)
+
+	modelRatings, err := LoadModelRatings(conn, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type groupKey struct{ Model, Trait, Dimension string }
+	type group struct {
+		result       ModelAgreement
+		model, human []float64
+		distances    []float64
+		unknown      map[string]bool
+	}
+	groups := map[groupKey]*group{}
+
+	for _, r := range modelRatings {
+		for _, dimension := range Dimensions {
+			human, ok := consensus[consensusKey{r.PhysicianID, r.Trait, dimension}]
+			if !ok {
+				continue
+			}
+
+			key := groupKey{r.ModelName, r.Trait, dimension}
+			g := groups[key]
+			if g == nil {
+				g = &group{
+					result: ModelAgreement{
+						Model:     r.ModelName,
+						Trait:     r.Trait,
+						Dimension: dimension,
+						Confusion: map[string]map[string]int{},
+					},
+					unknown: map[string]bool{},
+				}
+				groups[key] = g
+			}
+
+			label := r.Label(dimension)
+			score, known := modelValue(r.Parsed(dimension), labelMap, dimension, label)
+			if !known {
+				g.unknown[label] = true
+				continue
+			}
+			if score == nil {
+				g.result.Excluded++
+				continue
+			}
+
+			g.model = append(g.model, score.Value)
+			g.human = append(g.human, human)
+			g.distances = append(g.distances, rangeDistance(*score, human))
+			if g.result.Confusion[label] == nil {
+				g.result.Confusion[label] = map[string]int{}
+			}
+			g.result.Confusion[label][strconv.FormatFloat(human, 'g', -1, 64)]++
+		}
+	}
+
+	report := &ModelAgreementReport{
+		Filter:   filter,
+		Mappings: mappings,
+		Results:  []ModelAgreement{},
+	}
+	for _, g := range groups {
+		result := g.result
+		result.Units = len(g.model)
+		result.UnknownLabels = []string{}
+		for label := range g.unknown {
+			result.UnknownLabels = append(result.UnknownLabels, label)
+		}
+		sort.Strings(result.UnknownLabels)
+
+		if result.Units > 0 {
+			exact, withinOne := 0, 0
+			for _, distance := range g.distances {
+				if distance == 0 {
+					exact++
+				}
+				if distance <= 1 {
+					withinOne++
+				}
+			}
+			result.ExactAgreement = stats.Float(float64(exact)/float64(result.Units), true)
+			result.WithinOne = stats.Float(float64(withinOne)/float64(result.Units), true)
+		}
+		result.MAE = stats.Float(stats.MeanAbsoluteError(g.model, g.human))
+		result.Spearman = stats.Float(stats.Spearman(g.model, g.human))
+		report.Results = append(report.Results, result)
+	}
+
+	sort.Slice(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.Trait != b.Trait {
+			return a.Trait < b.Trait
+		}
+		return a.Dimension < b.Dimension
+	})
+	return report, nil
+}
+
+// WriteModelAgreementCSV 以CSV格式输出报告摘要（混淆矩阵仅在JSON中提供）
+func WriteModelAgreementCSV(w io.Writer, report *ModelAgreementReport) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"model", "trait", "dimension", "units", "excluded", "unknown_labels",
+		"exact_agreement", "within_one", "mae", "spearman",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range report.Results {
+		err := writer.Write([]string{
+			r.Model, r.Trait, r.Dimension, strconv.Itoa(r.Units), strconv.Itoa(r.Excluded),
+			fmt.Sprint(len(r.UnknownLabels)),
+			csvFloat(r.ExactAgreement), csvFloat(r.WithinOne), csvFloat(r.MAE), csvFloat(r.Spearman),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 4, 64)
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/phyreview_annotator/models"
+)
+
+func TestModelValueAndRangeDistance(t *testing.T) {
+	value := 4.0
+	labelMap := LabelMap{DimensionScore: {"fairly high": &value, "n/a": nil}}
+	lowToModerate := &models.NormalizedScore{Low: 2, High: 3, Value: 2.5, IsRange: true}
+
+	tests := []struct {
+		name      string
+		parsed    *models.NormalizedScore
+		label     string
+		human     float64
+		known     bool
+		excluded  bool
+		distance  float64
+		wantValue float64
+	}{
+		// 区间覆盖2和3，中点2.5只用于MAE和相关系数
+		{"range lower bound", lowToModerate, "Low to Moderate", 2, true, false, 0, 2.5},
+		{"range upper bound", lowToModerate, "Low to Moderate", 3, true, false, 0, 2.5},
+		{"range within one", lowToModerate, "Low to Moderate", 4, true, false, 1, 2.5},
+		{"range outside", lowToModerate, "Low to Moderate", 5, true, false, 2, 2.5},
+		{"range below", lowToModerate, "Low to Moderate", 1, true, false, 1, 2.5},
+		{"consensus between raters", lowToModerate, "Low to Moderate", 3.5, true, false, 0.5, 2.5},
+		{"single label", &models.NormalizedScore{Low: 5, High: 5, Value: 5}, "Very High", 4, true, false, 1, 5},
+		{"no evidence", &models.NormalizedScore{NoEvidence: true}, "No Evidence", 3, true, true, 0, 0},
+		// 解析器不认识的标签查映射表，映射值为单点
+		{"mapped label", nil, "Fairly High", 3, true, false, 1, 4},
+		{"mapped to null", nil, "N/A", 3, true, true, 0, 0},
+		{"unknown label", nil, "Somewhat", 3, false, true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, known := modelValue(tt.parsed, labelMap, DimensionScore, tt.label)
+			if known != tt.known {
+				t.Fatalf("known = %v, want %v", known, tt.known)
+			}
+			if (score == nil) != tt.excluded {
+				t.Fatalf("score = %+v, want excluded %v", score, tt.excluded)
+			}
+			if score == nil {
+				return
+			}
+			if score.Value != tt.wantValue {
+				t.Errorf("value = %v, want %v", score.Value, tt.wantValue)
+			}
+			if got := rangeDistance(*score, tt.human); got != tt.distance {
+				t.Errorf("distance = %v, want %v", got, tt.distance)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package attentioncheck
+
+import (
+	"database/sql"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/qualification"
+)
+
+// Config 注意力检查的插入比例和告警阈值
+type Config struct {
+	Rate        float64 `json:"rate"`         // 领取任务时插入检查任务的概率
+	MinAccuracy float64 `json:"min_accuracy"` // 近期检查中与参考标签相差不超过1分的最低比例
+	MinResults  int     `json:"min_results"`  // 至少有多少条检查结果才判断是否告警
+	Window      int     `json:"window"`       // 计算准确率时只看最近多少条检查结果，0表示全部
+}
+
+// LoadConfig 从环境变量读取配置：ATTENTION_CHECK_RATE（默认0.05，0表示关闭）、
+// ATTENTION_CHECK_MIN_ACCURACY（默认0.8）、ATTENTION_CHECK_MIN_RESULTS（默认5）、ATTENTION_CHECK_WINDOW（默认20）
+func LoadConfig() Config {
+	cfg := Config{Rate: 0.05, MinAccuracy: 0.8, MinResults: 5, Window: 20}
+	if value := os.Getenv("ATTENTION_CHECK_RATE"); value != "" {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 && v <= 1 {
+			cfg.Rate = v
+		} else {
+			log.Printf("Warning: invalid ATTENTION_CHECK_RATE %q, using %g", value, cfg.Rate)
+		}
+	}
+	if value := os.Getenv("ATTENTION_CHECK_MIN_ACCURACY"); value != "" {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 && v <= 1 {
+			cfg.MinAccuracy = v
+		} else {
+			log.Printf("Warning: invalid ATTENTION_CHECK_MIN_ACCURACY %q, using %g", value, cfg.MinAccuracy)
+		}
+	}
+	if value := os.Getenv("ATTENTION_CHECK_MIN_RESULTS"); value != "" {
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			cfg.MinResults = v
+		} else {
+			log.Printf("Warning: invalid ATTENTION_CHECK_MIN_RESULTS %q, using %d", value, cfg.MinResults)
+		}
+	}
+	if value := os.Getenv("ATTENTION_CHECK_WINDOW"); value != "" {
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			cfg.Window = v
+		} else {
+			log.Printf("Warning: invalid ATTENTION_CHECK_WINDOW %q, using %d", value, cfg.Window)
+		}
+	}
+	return cfg
+}
+
+// Inject 以cfg.Rate的概率为评分者创建一个指派给本人的检查任务，之后和普通指派任务一样被领取。
+// 评分者持有未过期的租约或还有未完成的检查任务时不插入。
+// 检查任务选自用于注意力检查的参考标签所在的医生，跳过评分者已参与过的医生和资格测试中见过的医生。
+// 返回是否插入了检查任务
+func Inject(q db.Querier, cfg Config, evaluator string, now time.Time) (bool, error) {
+	if cfg.Rate <= 0 {
+		return false, nil
+	}
+
+	var busy bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM tasks WHERE lease_owner = $1 AND lease_expires_at > $2
+		) OR EXISTS (
+			SELECT 1 FROM attention_checks a
+			JOIN tasks t ON t.id = a.task_id AND t.physician_id = a.physician_id
+			WHERE a.evaluator = $1 AND t.status IN ($3, $4, $5)
+		)
+	`, evaluator, now, models.TaskStatusPending, models.TaskStatusInProgress, models.TaskStatusReopened).Scan(&busy)
+	if err != nil || busy {
+		return false, err
+	}
+	if rand.Float64() >= cfg.Rate {
+		return false, nil
+	}
+
+	var physicianID int
+	err = q.QueryRow(`
+		SELECT g.physician_id FROM gold_labels g
+		WHERE g.attention_check
+		AND NOT EXISTS (
+			SELECT 1 FROM tasks t WHERE t.physician_id = g.physician_id AND t.assigned_to = $1
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM human_annotations h WHERE h.physician_id = g.physician_id AND h.evaluator = $1
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM qualification_answers x
+			JOIN qualification_attempts a ON a.id = x.attempt_id
+			JOIN gold_labels o ON o.id = x.gold_label_id
+			WHERE a.evaluator = $1 AND o.physician_id = g.physician_id
+		)
+		GROUP BY g.physician_id
+		ORDER BY random()
+		LIMIT 1
+	`, evaluator).Scan(&physicianID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// 任务ID沿用该医生已有任务的编号顺序，并发插入同一医生时放弃本次
+	var taskID int
+	err = q.QueryRow(`
+		INSERT INTO tasks (id, physician_id, status, assigned_to, timestamp)
+		SELECT COALESCE(MAX(id), 0) + 1, $1, $2, $3, $4 FROM tasks WHERE physician_id = $1
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, physicianID, models.TaskStatusPending, evaluator, now).Scan(&taskID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = q.Exec(`
+		INSERT INTO attention_checks (evaluator, task_id, physician_id, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, evaluator, taskID, physicianID, now)
+	return err == nil, err
+}
+
+// Record 如果任务是该评分者的检查任务且该trait有参考标签，记录评分和当时的参考标签。
+// 每个trait只记录第一次提交。返回是否记录了新结果
+func Record(q db.Querier, annotation models.HumanAnnotation, timestamp time.Time) (bool, error) {
+	result, err := q.Exec(`
+		INSERT INTO attention_check_results
+		(check_id, trait, score, consistency, sufficiency,
+			expected_score, expected_consistency, expected_sufficiency, answered_at)
+		SELECT a.id, g.trait, $5, $6, $7, g.score, g.consistency, g.sufficiency, $8
+		FROM attention_checks a
+		JOIN gold_labels g ON g.physician_id = a.physician_id AND g.attention_check
+		WHERE a.task_id = $1 AND a.physician_id = $2 AND a.evaluator = $3 AND g.trait = $4
+		ON CONFLICT (check_id, trait) DO NOTHING
+	`, annotation.TaskID, annotation.PhysicianID, annotation.Evaluator, annotation.Trait,
+		annotation.Score, annotation.Consistency, annotation.Sufficiency, timestamp)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// Summary 一位评分者的注意力检查情况
+type Summary struct {
+	Evaluator string   `json:"evaluator"`
+	Checks    int      `json:"checks"`  // 插入的检查任务数
+	Results   int      `json:"results"` // 已提交的检查结果数（每个trait一条）
+	Recent    int      `json:"recent"`  // 计算准确率用到的最近结果数
+	Accuracy  *float64 `json:"accuracy"`
+	Kappa     *float64 `json:"kappa"`
+	Flagged   bool     `json:"flagged"` // 准确率低于阈值
+}
+
+// Summarize 按评分者汇总检查结果，准确率只看最近cfg.Window条；evaluator为空时汇总全部评分者
+func Summarize(q db.Querier, cfg Config, evaluator string) ([]Summary, error) {
+	rows, err := q.Query(`
+		SELECT a.evaluator, COUNT(*) FROM attention_checks a
+		WHERE ($1 = '' OR a.evaluator = $1)
+		GROUP BY a.evaluator
+		ORDER BY a.evaluator
+	`, evaluator)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []Summary{}
+	index := map[string]int{}
+	for rows.Next() {
+		var s Summary
+		if err := rows.Scan(&s.Evaluator, &s.Checks); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[s.Evaluator] = len(summaries)
+		summaries = append(summaries, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(`
+		SELECT a.evaluator, r.score, r.consistency, r.sufficiency,
+			r.expected_score, r.expected_consistency, r.expected_sufficiency
+		FROM attention_check_results r JOIN attention_checks a ON a.id = r.check_id
+		WHERE ($1 = '' OR a.evaluator = $1)
+		ORDER BY a.evaluator, r.answered_at DESC, r.id DESC
+	`, evaluator)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recent := map[string][]qualification.Answer{}
+	for rows.Next() {
+		var name string
+		var answer qualification.Answer
+		err := rows.Scan(&name, &answer.Given.Score, &answer.Given.Consistency, &answer.Given.Sufficiency,
+			&answer.Reference.Score, &answer.Reference.Consistency, &answer.Reference.Sufficiency)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[name]
+		if !ok {
+			continue
+		}
+		summaries[i].Results++
+		if cfg.Window == 0 || len(recent[name]) < cfg.Window {
+			recent[name] = append(recent[name], answer)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range summaries {
+		s := &summaries[i]
+		score := qualification.Score(recent[s.Evaluator])
+		s.Recent, s.Accuracy, s.Kappa = score.Items, score.WithinOne, score.Kappa
+		s.Flagged = s.Recent >= cfg.MinResults && s.Accuracy != nil && *s.Accuracy < cfg.MinAccuracy
+	}
+	return summaries, nil
+}
+
+// Evaluate 评分者近期准确率低于阈值且没有未处理的告警时生成告警，返回新告警，没有时返回nil
+func Evaluate(q db.Querier, cfg Config, evaluator string, now time.Time) (*models.AttentionCheckAlert, error) {
+	summaries, err := Summarize(q, cfg, evaluator)
+	if err != nil || len(summaries) == 0 || !summaries[0].Flagged {
+		return nil, err
+	}
+
+	s := summaries[0]
+	alert := models.AttentionCheckAlert{Evaluator: evaluator, Accuracy: *s.Accuracy, Results: s.Recent, CreatedAt: now}
+	err = q.QueryRow(`
+		INSERT INTO attention_check_alerts (evaluator, accuracy, results, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (evaluator) WHERE acknowledged_at IS NULL DO NOTHING
+		RETURNING id
+	`, alert.Evaluator, alert.Accuracy, alert.Results, alert.CreatedAt).Scan(&alert.ID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// ListAlerts 列出告警，最新的在前；all为false时只列出未处理的告警
+func ListAlerts(q db.Querier, all bool) ([]models.AttentionCheckAlert, error) {
+	rows, err := q.Query(`
+		SELECT id, evaluator, accuracy, results, created_at, COALESCE(acknowledged_by, ''), acknowledged_at
+		FROM attention_check_alerts
+		WHERE $1 OR acknowledged_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`, all)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.AttentionCheckAlert{}
+	for rows.Next() {
+		var alert models.AttentionCheckAlert
+		var acknowledgedAt sql.NullTime
+		err := rows.Scan(&alert.ID, &alert.Evaluator, &alert.Accuracy, &alert.Results,
+			&alert.CreatedAt, &alert.AcknowledgedBy, &acknowledgedAt)
+		if err != nil {
+			return nil, err
+		}
+		if acknowledgedAt.Valid {
+			alert.AcknowledgedAt = &acknowledgedAt.Time
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// Acknowledge 标记告警已处理，告警不存在或已处理时返回sql.ErrNoRows
+func Acknowledge(q db.Querier, id int, by string, now time.Time) error {
+	result, err := q.Exec(`
+		UPDATE attention_check_alerts SET acknowledged_by = $1, acknowledged_at = $2
+		WHERE id = $3 AND acknowledged_at IS NULL
+	`, by, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
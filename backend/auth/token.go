@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 令牌相关错误
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims 令牌中携带的声明
+type Claims struct {
+	Username  string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var (
+	secretOnce sync.Once
+	secret     []byte
+)
+
+// signingSecret 读取AUTH_SECRET，未配置时生成进程级随机密钥
+func signingSecret() []byte {
+	secretOnce.Do(func() {
+		if s := os.Getenv("AUTH_SECRET"); s != "" {
+			secret = []byte(s)
+			return
+		}
+		log.Println("Warning: AUTH_SECRET not set, using a random secret; tokens will not survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal("Failed to generate auth secret: ", err)
+		}
+	})
+	return secret
+}
+
+// TokenTTL 令牌有效期，可通过AUTH_TOKEN_TTL配置（如 "12h"）
+func TokenTTL() time.Duration {
+	if v := os.Getenv("AUTH_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Warning: invalid AUTH_TOKEN_TTL %q, using default", v)
+	}
+	return 12 * time.Hour
+}
+
+// IssueToken 为用户签发令牌，格式为 base64(payload).base64(hmac)
+func IssueToken(username string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(TokenTTL())
+	payload, err := json.Marshal(Claims{
+		Username:  username,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(encoded), expiresAt, nil
+}
+
+// ParseToken 校验签名和有效期并返回声明
+func ParseToken(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(sign(parts[0])), []byte(parts[1])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Username == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+func sign(data string) string {
+	mac := hmac.New(sha256.New, signingSecret())
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,219 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// 归档格式：tar.gz中第一个文件为manifest.json，之后每个表一个JSONL文件
+const (
+	FormatName    = "phyreview-annotations"
+	FormatVersion = 1
+	manifestFile  = "manifest.json"
+)
+
+// Manifest 归档说明：格式版本、来源数据库和每个表文件的行数与SHA-256
+type Manifest struct {
+	Format        string       `json:"format"`
+	Version       int          `json:"version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Database      string       `json:"database"`
+	SchemaVersion int          `json:"schema_version"`
+	Tables        []TableEntry `json:"tables"`
+}
+
+// TableEntry 归档中的一个表文件
+type TableEntry struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// Archive 已解压并校验过的归档，表文件保存在临时目录中
+type Archive struct {
+	Manifest Manifest
+	dir      string
+}
+
+// writeArchive 将manifest和已写好的表文件打包为tar.gz
+func writeArchive(w io.Writer, manifest Manifest, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: manifestFile, Mode: 0o600, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	for _, table := range manifest.Tables {
+		if err := addFile(tw, filepath.Join(dir, table.File), table.File, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, src, name string, modTime time.Time) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0o600, Size: info.Size(), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// ReadArchive 解压归档到临时目录，校验格式版本和每个表文件的行数与SHA-256。
+// 校验全部通过后才返回，使用完毕需调用Close
+func ReadArchive(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	if header.Name != manifestFile {
+		return nil, fmt.Errorf("archive must start with %s, found %s", manifestFile, header.Name)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", manifestFile, err)
+	}
+	if manifest.Format != FormatName {
+		return nil, fmt.Errorf("unknown archive format %q", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("archive format version %d is not supported (this build reads up to %d)", manifest.Version, FormatVersion)
+	}
+
+	expected := map[string]TableEntry{}
+	for _, table := range manifest.Tables {
+		if table.File != path.Base(table.File) {
+			return nil, fmt.Errorf("invalid file name %q in manifest", table.File)
+		}
+		expected[table.File] = table
+	}
+
+	dir, err := os.MkdirTemp("", "phyreview-restore-")
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{Manifest: manifest, dir: dir}
+
+	seen := map[string]bool{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			archive.Close()
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		table, ok := expected[header.Name]
+		if !ok || seen[header.Name] {
+			archive.Close()
+			return nil, fmt.Errorf("unexpected file %s in archive", header.Name)
+		}
+		seen[header.Name] = true
+
+		if err := extractFile(tr, filepath.Join(dir, header.Name), table); err != nil {
+			archive.Close()
+			return nil, fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+
+	for _, table := range manifest.Tables {
+		if !seen[table.File] {
+			archive.Close()
+			return nil, fmt.Errorf("archive is missing %s", table.File)
+		}
+	}
+	return archive, nil
+}
+
+// extractFile 写出一个表文件并校验行数和SHA-256
+func extractFile(r io.Reader, dst string, table TableEntry) error {
+	file, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &lineCounter{}
+	if _, err := io.Copy(io.MultiWriter(file, hash, counter), r); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != table.SHA256 {
+		return fmt.Errorf("checksum mismatch: manifest %s, file %s", table.SHA256, sum)
+	}
+	if counter.lines != table.Rows {
+		return fmt.Errorf("row count mismatch: manifest %d, file %d", table.Rows, counter.lines)
+	}
+	return nil
+}
+
+// lineCounter 统计写入的换行符数量，即JSONL的行数
+type lineCounter struct {
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\n' {
+			c.lines++
+		}
+	}
+	return len(p), nil
+}
+
+// Table 返回表在归档中的文件，不存在时ok为false
+func (a *Archive) Table(name string) (entry TableEntry, path string, ok bool) {
+	for _, table := range a.Manifest.Tables {
+		if table.Name == name {
+			return table, filepath.Join(a.dir, table.File), true
+		}
+	}
+	return TableEntry{}, "", false
+}
+
+// Close 删除解压出的临时文件
+func (a *Archive) Close() error {
+	return os.RemoveAll(a.dir)
+}
@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveFile 手工构造归档时的一个文件
+type archiveFile struct {
+	name string
+	data string
+}
+
+// tableEntry 按内容计算行数和SHA-256
+func tableEntry(name, data string) TableEntry {
+	sum := sha256.Sum256([]byte(data))
+	return TableEntry{Name: name, File: name + ".jsonl", Rows: strings.Count(data, "\n"), SHA256: hex.EncodeToString(sum[:])}
+}
+
+func testManifest(tables ...TableEntry) Manifest {
+	return Manifest{Format: FormatName, Version: FormatVersion, CreatedAt: time.Now().UTC(), Database: "test", Tables: tables}
+}
+
+// buildArchive 不经过writeArchive直接打包，用于构造被篡改的归档
+func buildArchive(t *testing.T, manifest Manifest, files ...archiveFile) *bytes.Buffer {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range append([]archiveFile{{manifestFile, string(data)}}, files...) {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o600, Size: int64(len(file.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	contents := map[string]string{
+		"physicians":  `{"id":1,"npi":1234567890,"physician_npi":1234567890}` + "\n",
+		"tasks":       `{"id":1,"physician_id":1,"physician_npi":1234567890}` + "\n" + `{"id":2,"physician_id":1,"physician_npi":1234567890}` + "\n",
+		"gold_labels": "",
+	}
+	dir := t.TempDir()
+	var entries []TableEntry
+	for _, name := range []string{"physicians", "tasks", "gold_labels"} {
+		entry := tableEntry(name, contents[name])
+		if err := os.WriteFile(filepath.Join(dir, entry.File), []byte(contents[name]), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, testManifest(entries...), dir); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	for name, want := range contents {
+		entry, path, ok := archive.Table(name)
+		if !ok {
+			t.Fatalf("table %s missing", name)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want || entry.Rows != strings.Count(want, "\n") {
+			t.Errorf("%s: got %q (%d rows), want %q", name, got, entry.Rows, want)
+		}
+	}
+	if _, _, ok := archive.Table("users"); ok {
+		t.Error("table users should not be in the archive")
+	}
+
+	// Close删除解压出的临时文件
+	_, path, _ := archive.Table("tasks")
+	archive.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("extracted file still exists after Close: %v", err)
+	}
+}
+
+func TestReadArchiveRejects(t *testing.T) {
+	rows := `{"id":1}` + "\n" + `{"id":2}` + "\n"
+	tasks := tableEntry("tasks", rows)
+
+	badChecksum := tasks
+	badChecksum.SHA256 = strings.Repeat("0", 64)
+	badRows := tasks
+	badRows.Rows = 3
+	traversal := tasks
+	traversal.File = "../x"
+	newer := testManifest(tasks)
+	newer.Version = FormatVersion + 1
+	otherFormat := testManifest(tasks)
+	otherFormat.Format = "something-else"
+
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+		wantErr string
+	}{
+		{"checksum mismatch", buildArchive(t, testManifest(badChecksum), archiveFile{"tasks.jsonl", rows}), "checksum mismatch"},
+		{"row count mismatch", buildArchive(t, testManifest(badRows), archiveFile{"tasks.jsonl", rows}), "row count mismatch"},
+		{"path traversal", buildArchive(t, testManifest(traversal), archiveFile{"../x", rows}), `invalid file name "../x"`},
+		{"missing table file", buildArchive(t, testManifest(tasks)), "archive is missing tasks.jsonl"},
+		{
+			name:    "duplicate table file",
+			archive: buildArchive(t, testManifest(tasks), archiveFile{"tasks.jsonl", rows}, archiveFile{"tasks.jsonl", rows}),
+			wantErr: "unexpected file tasks.jsonl",
+		},
+		{"file not in manifest", buildArchive(t, testManifest(tasks), archiveFile{"tasks.jsonl", rows}, archiveFile{"users.jsonl", rows}), "unexpected file users.jsonl"},
+		{"newer format version", buildArchive(t, newer, archiveFile{"tasks.jsonl", rows}), "format version 2 is not supported"},
+		{"unknown format", buildArchive(t, otherFormat, archiveFile{"tasks.jsonl", rows}), "unknown archive format"},
+		{"not gzip", bytes.NewBufferString("plain text"), "not a gzip archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := ReadArchive(tt.archive)
+			if err == nil {
+				archive.Close()
+				t.Fatalf("expected an error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/maintenance"
+)
+
+// annotatedPhysicians 有任务或标注数据的医生
+const annotatedPhysicians = `
+	SELECT physician_id FROM tasks
+	UNION SELECT physician_id FROM trait_progress
+	UNION SELECT physician_id FROM human_annotations
+	UNION SELECT physician_id FROM machine_annotation_evaluation
+	UNION SELECT physician_id FROM gold_labels`
+
+// evaluatedModelAnnotations 被人工评价过的模型标注
+const evaluatedModelAnnotations = `
+	SELECT model_annotation_id FROM machine_annotation_evaluation
+	UNION SELECT model_annotation_id FROM machine_evaluation_revisions`
+
+// table 归档中的一个表。导出的每行都带physician_npi，恢复时据此重新映射physician_id；
+// physician_id为空的行无法映射，不导出。users、models、qualification_attempts和
+// attention_check_alerts与医生无关，不带physician_npi
+type table struct {
+	Name  string
+	Query string
+}
+
+// tables 按恢复顺序排列：被引用的表在前。
+// consensus_runs、consensus_labels和annotator_reliability有意不归档：它们完全由人类标注计算得出，
+// 恢复后用cmd/consensus按需要的方法和参数重新计算即可
+var tables = []table{
+	{"users", `SELECT t.* FROM users t ORDER BY t.id`},
+	{"physicians", `SELECT p.*, p.npi AS physician_npi FROM physicians p
+		WHERE p.id IN (` + annotatedPhysicians + `) ORDER BY p.id`},
+	// 只导出被人类证据引用的评论，恢复到空库时引用才能找到评论；其余评论从源文件重新导入
+	{"reviews", withNPI("reviews", `WHERE t.review_index IS NOT NULL
+		AND t.id IN (SELECT review_id FROM human_annotation_citations) ORDER BY t.id`)},
+	{"models", `SELECT m.* FROM models m
+		WHERE m.id IN (SELECT model_id FROM model_annotations WHERE id IN (` + evaluatedModelAnnotations + `))
+		ORDER BY m.id`},
+	{"model_annotations", withNPI("model_annotations", `WHERE t.id IN (`+evaluatedModelAnnotations+`)`)},
+	{"tasks", withNPI("tasks", "")},
+	{"task_status_history", withNPI("task_status_history", "")},
+	{"trait_progress", withNPI("trait_progress", "")},
+	{"human_annotations", withNPI("human_annotations", "")},
+	{"human_annotation_revisions", withNPI("human_annotation_revisions", "")},
+	{"human_annotation_citations", `SELECT t.*, p.npi AS physician_npi, r.review_index AS review_index
+		FROM human_annotation_citations t
+		JOIN physicians p ON p.id = t.physician_id
+		JOIN reviews r ON r.id = t.review_id`},
+	{"machine_annotation_evaluation", withNPI("machine_annotation_evaluation", "")},
+	{"machine_evaluation_revisions", withNPI("machine_evaluation_revisions", "")},
+	{"adjudication_tasks", withNPI("adjudication_tasks", "")},
+	{"adjudicated_labels", withNPI("adjudicated_labels", "")},
+	{"gold_labels", withNPI("gold_labels", "")},
+	{"qualification_attempts", `SELECT t.* FROM qualification_attempts t ORDER BY t.id`},
+	{"qualification_answers", `SELECT t.*, p.npi AS physician_npi
+		FROM qualification_answers t
+		JOIN gold_labels g ON g.id = t.gold_label_id
+		JOIN physicians p ON p.id = g.physician_id`},
+	{"attention_checks", withNPI("attention_checks", "")},
+	{"attention_check_results", `SELECT t.*, p.npi AS physician_npi
+		FROM attention_check_results t
+		JOIN attention_checks a ON a.id = t.check_id
+		JOIN physicians p ON p.id = a.physician_id`},
+	{"attention_check_alerts", `SELECT t.* FROM attention_check_alerts t ORDER BY t.id`},
+}
+
+func withNPI(name, where string) string {
+	return fmt.Sprintf(`SELECT t.*, p.npi AS physician_npi FROM %s t
+		JOIN physicians p ON p.id = t.physician_id %s`, name, where)
+}
+
+// Export 在一个只读的可重复读事务中导出标注数据及其引用的医生和模型，写为tar.gz归档
+func Export(conn *sql.DB, w io.Writer) (Manifest, error) {
+	manifest := Manifest{Format: FormatName, Version: FormatVersion, CreatedAt: time.Now().UTC()}
+
+	var err error
+	if manifest.Database, err = maintenance.DatabaseName(conn); err != nil {
+		return manifest, err
+	}
+	if manifest.SchemaVersion, err = db.SchemaVersion(conn); err != nil {
+		return manifest, err
+	}
+
+	dir, err := os.MkdirTemp("", "phyreview-backup-")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(dir)
+
+	tx, err := conn.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return manifest, err
+	}
+	defer tx.Rollback()
+
+	for _, t := range tables {
+		entry, err := exportTable(tx, t, dir)
+		if err != nil {
+			return manifest, fmt.Errorf("export %s: %w", t.Name, err)
+		}
+		manifest.Tables = append(manifest.Tables, entry)
+	}
+
+	return manifest, writeArchive(w, manifest, dir)
+}
+
+// exportTable 将查询结果逐行写为JSONL，同时计算SHA-256
+func exportTable(tx *sql.Tx, t table, dir string) (TableEntry, error) {
+	entry := TableEntry{Name: t.Name, File: t.Name + ".jsonl"}
+
+	rows, err := tx.Query(fmt.Sprintf(`SELECT row_to_json(x)::text FROM (%s) x`, t.Query))
+	if err != nil {
+		return entry, err
+	}
+	defer rows.Close()
+
+	file, err := os.OpenFile(filepath.Join(dir, entry.File), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return entry, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(file, hash))
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return entry, err
+		}
+		w.WriteString(line)
+		w.WriteByte('\n')
+		entry.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return entry, err
+	}
+	if err := w.Flush(); err != nil {
+		return entry, err
+	}
+
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry, nil
+}
+
+// ExportFile 导出到path：先写临时文件，成功后再改名，避免留下不完整的归档
+func ExportFile(conn *sql.DB, path string) (Manifest, error) {
+	tmp := path + ".partial"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest, err := Export(conn, file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return manifest, err
+	}
+	return manifest, os.Rename(tmp, path)
+}
+
+// ExportToDir 破坏性操作前的备份：导出到 dir/<数据库名>-<时间>.tar.gz，返回归档路径
+func ExportToDir(conn *sql.DB, dir string) (string, Manifest, error) {
+	name, err := maintenance.DatabaseName(conn)
+	if err != nil {
+		return "", Manifest{}, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", Manifest{}, err
+	}
+	path, err := filepath.Abs(filepath.Join(dir, fmt.Sprintf("%s-%s.tar.gz", name, time.Now().Format("20060102-150405"))))
+	if err != nil {
+		return "", Manifest{}, err
+	}
+	manifest, err := ExportFile(conn, path)
+	return path, manifest, err
+}
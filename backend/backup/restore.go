@@ -0,0 +1,568 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TableResult 恢复一个表的结果：新插入的行数和已存在而跳过的行数
+type TableResult struct {
+	Name     string
+	Inserted int
+	Skipped  int
+}
+
+// historyKey task_status_history没有唯一约束，按这些列判断记录是否已存在
+var historyKey = []string{"task_id", "physician_id", "from_status", "to_status", "timestamp"}
+
+// restorer 恢复过程中的ID映射：用户按username，医生按NPI，模型按(provider, version, prompt_version)，
+// 模型标注按(physician_id, model_id, trait)，仲裁任务按(physician_id, trait, created_at)，
+// 参考标签按(physician_id, trait)，资格测试按(evaluator, started_at)，注意力检查按(task_id, physician_id)
+type restorer struct {
+	tx              *sql.Tx
+	physicians      map[string]int // NPI -> 目标库physicians.id
+	models          map[string]int // 归档中的models.id -> 目标库models.id
+	annotations     map[string]int // 归档中的model_annotations.id -> 目标库model_annotations.id
+	adjudications   map[string]int // 归档中的adjudication_tasks.id -> 目标库adjudication_tasks.id
+	goldLabels      map[string]int // 归档中的gold_labels.id -> 目标库gold_labels.id
+	attempts        map[string]int // 归档中的qualification_attempts.id -> 目标库id，0表示跳过
+	attentionChecks map[string]int // 归档中的attention_checks.id -> 目标库attention_checks.id
+	columns         map[string]map[string]bool
+}
+
+// Restore 在一个事务中把归档恢复到当前数据库，目标库可以为空也可以已有数据。
+// 序列生成的ID全部重新分配；已存在的医生、模型和标注保持不变，对应的归档行跳过。
+// dryRun时执行全部写入后回滚
+func Restore(conn *sql.DB, archive *Archive, dryRun bool) ([]TableResult, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	r := &restorer{
+		tx:              tx,
+		physicians:      map[string]int{},
+		models:          map[string]int{},
+		annotations:     map[string]int{},
+		adjudications:   map[string]int{},
+		goldLabels:      map[string]int{},
+		attempts:        map[string]int{},
+		attentionChecks: map[string]int{},
+		columns:         map[string]map[string]bool{},
+	}
+
+	var results []TableResult
+	for _, t := range tables {
+		entry, path, ok := archive.Table(t.Name)
+		if !ok {
+			continue
+		}
+		result := TableResult{Name: t.Name}
+		err := eachRow(path, func(row map[string]interface{}) error {
+			inserted, err := r.restoreRow(t.Name, row)
+			if inserted {
+				result.Inserted++
+			} else {
+				result.Skipped++
+			}
+			return err
+		})
+		if err != nil {
+			return results, fmt.Errorf("restore %s: %w", entry.File, err)
+		}
+		results = append(results, result)
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+
+// eachRow 逐行解码JSONL，数字保留为json.Number以免精度丢失
+func eachRow(path string, fn func(row map[string]interface{}) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(row); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// restoreRow 恢复一行，返回是否新插入
+func (r *restorer) restoreRow(name string, row map[string]interface{}) (bool, error) {
+	switch name {
+	case "users":
+		return r.restoreUser(row)
+	case "attention_check_alerts":
+		return r.restoreAttentionAlert(row)
+	case "physicians":
+		return r.restorePhysician(row)
+	case "models":
+		return r.restoreModel(row)
+	case "qualification_attempts":
+		return r.restoreQualificationAttempt(row)
+	}
+
+	// 其余表按NPI映射physician_id
+	npi := key(row["physician_npi"])
+	physicianID, ok := r.physicians[npi]
+	if !ok {
+		id, err := r.physicianByNPI(npi)
+		if err != nil {
+			return false, err
+		}
+		physicianID = id
+	}
+	row["physician_id"] = physicianID
+
+	switch name {
+	case "model_annotations":
+		return r.restoreModelAnnotation(row)
+	case "adjudication_tasks":
+		return r.restoreAdjudicationTask(row)
+	case "gold_labels":
+		return r.restoreGoldLabel(row)
+	case "attention_checks":
+		return r.restoreAttentionCheck(row)
+	}
+
+	if value, ok := row["model_annotation_id"]; ok && value != nil {
+		id, ok := r.annotations[key(value)]
+		if !ok {
+			return false, fmt.Errorf("model annotation %s is not in the archive", key(value))
+		}
+		row["model_annotation_id"] = id
+	}
+
+	// 被引用的评论在引用之前恢复（目标库已有的评论保持不变），按(physician_id, review_index)查找
+	if name == "human_annotation_citations" {
+		id, err := r.reviewByIndex(physicianID, key(row["review_index"]))
+		if err != nil {
+			return false, err
+		}
+		row["review_id"] = id
+	}
+
+	if name == "adjudicated_labels" {
+		id, ok := r.adjudications[key(row["adjudication_id"])]
+		if !ok {
+			return false, fmt.Errorf("adjudication %s is not in the archive", key(row["adjudication_id"]))
+		}
+		row["adjudication_id"] = id
+	}
+
+	if name == "qualification_answers" {
+		attemptID, ok := r.attempts[key(row["attempt_id"])]
+		if !ok {
+			return false, fmt.Errorf("qualification attempt %s is not in the archive", key(row["attempt_id"]))
+		}
+		if attemptID == 0 {
+			return false, nil
+		}
+		goldLabelID, ok := r.goldLabels[key(row["gold_label_id"])]
+		if !ok {
+			return false, fmt.Errorf("gold label %s is not in the archive", key(row["gold_label_id"]))
+		}
+		row["attempt_id"], row["gold_label_id"] = attemptID, goldLabelID
+	}
+
+	if name == "attention_check_results" {
+		id, ok := r.attentionChecks[key(row["check_id"])]
+		if !ok {
+			return false, fmt.Errorf("attention check %s is not in the archive", key(row["check_id"]))
+		}
+		row["check_id"] = id
+	}
+
+	// tasks.id是医生下的任务号，不是序列，保留原值
+	if name != "tasks" {
+		delete(row, "id")
+	}
+
+	if name == "task_status_history" {
+		exists, err := r.exists(name, row, historyKey)
+		if err != nil || exists {
+			return false, err
+		}
+	}
+
+	_, inserted, err := r.insert(name, row, "ON CONFLICT DO NOTHING", false)
+	return inserted, err
+}
+
+// restoreUser 按username匹配已有用户，不存在时连同密码哈希、角色和资格插入；已有用户保持不变
+func (r *restorer) restoreUser(row map[string]interface{}) (bool, error) {
+	delete(row, "id")
+	_, inserted, err := r.insert("users", row, "ON CONFLICT (username) DO NOTHING", false)
+	return inserted, err
+}
+
+// restoreAttentionAlert 按(evaluator, created_at)匹配已有的告警；
+// 评分者在目标库中已有未处理的告警时，归档中未处理的告警跳过
+func (r *restorer) restoreAttentionAlert(row map[string]interface{}) (bool, error) {
+	delete(row, "id")
+	exists, err := r.exists("attention_check_alerts", row, []string{"evaluator", "created_at"})
+	if err != nil || exists {
+		return false, err
+	}
+	_, inserted, err := r.insert("attention_check_alerts", row, "ON CONFLICT DO NOTHING", false)
+	return inserted, err
+}
+
+// restorePhysician 按NPI匹配已有医生，不存在时插入
+func (r *restorer) restorePhysician(row map[string]interface{}) (bool, error) {
+	npi := key(row["npi"])
+	if npi == "" {
+		return false, fmt.Errorf("physician %s has no NPI", key(row["id"]))
+	}
+
+	var id int
+	err := r.tx.QueryRow(`SELECT id FROM physicians WHERE npi = $1`, npi).Scan(&id)
+	if err == nil {
+		r.physicians[npi] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("physicians", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.physicians[npi] = id
+	return true, nil
+}
+
+func (r *restorer) physicianByNPI(npi string) (int, error) {
+	if npi == "" {
+		return 0, fmt.Errorf("row has no physician NPI")
+	}
+	var id int
+	err := r.tx.QueryRow(`SELECT id FROM physicians WHERE npi = $1`, npi).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("physician with NPI %s is neither in the archive nor in the database", npi)
+	}
+	if err == nil {
+		r.physicians[npi] = id
+	}
+	return id, err
+}
+
+func (r *restorer) reviewByIndex(physicianID int, index string) (int, error) {
+	var id int
+	err := r.tx.QueryRow(`SELECT id FROM reviews WHERE physician_id = $1 AND review_index = $2`, physicianID, index).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("review %s of physician %d is neither in the archive nor in the database", index, physicianID)
+	}
+	return id, err
+}
+
+// restoreModel 按(provider, version, prompt_version)匹配已注册的模型，不存在时注册
+func (r *restorer) restoreModel(row map[string]interface{}) (bool, error) {
+	oldID := key(row["id"])
+
+	var id int
+	err := r.tx.QueryRow(`
+		SELECT id FROM models WHERE provider = $1 AND version = $2 AND prompt_version = $3
+	`, row["provider"], row["version"], row["prompt_version"]).Scan(&id)
+	if err == nil {
+		r.models[oldID] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("models", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.models[oldID] = id
+	return true, nil
+}
+
+// restoreModelAnnotation 按(physician_id, model_id, trait)匹配已有的模型标注，
+// 没有model_id的旧数据按model_name匹配；不存在时插入
+func (r *restorer) restoreModelAnnotation(row map[string]interface{}) (bool, error) {
+	oldID := key(row["id"])
+
+	var match []string
+	if value := row["model_id"]; value != nil {
+		id, ok := r.models[key(value)]
+		if !ok {
+			return false, fmt.Errorf("model %s is not in the archive", key(value))
+		}
+		row["model_id"] = id
+		match = []string{"physician_id", "model_id", "trait"}
+	} else {
+		match = []string{"physician_id", "model_name", "trait"}
+	}
+
+	var id int
+	query, args := whereEqual(match, row)
+	err := r.tx.QueryRow(`SELECT id FROM model_annotations WHERE `+query+` ORDER BY id LIMIT 1`, args...).Scan(&id)
+	if err == nil {
+		r.annotations[oldID] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("model_annotations", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.annotations[oldID] = id
+	return true, nil
+}
+
+// restoreAdjudicationTask 按(physician_id, trait, created_at)匹配已有的仲裁任务；
+// 目标库中同一医生同一trait已有未完成仲裁时，归档中未完成的仲裁合并到该任务
+func (r *restorer) restoreAdjudicationTask(row map[string]interface{}) (bool, error) {
+	oldID := key(row["id"])
+
+	var id int
+	query, args := whereEqual([]string{"physician_id", "trait", "created_at"}, row)
+	err := r.tx.QueryRow(`SELECT id FROM adjudication_tasks WHERE `+query+` ORDER BY id LIMIT 1`, args...).Scan(&id)
+	if err == sql.ErrNoRows && key(row["status"]) == "open" {
+		err = r.tx.QueryRow(`
+			SELECT id FROM adjudication_tasks WHERE physician_id = $1 AND trait = $2 AND status = 'open'
+		`, row["physician_id"], row["trait"]).Scan(&id)
+	}
+	if err == nil {
+		r.adjudications[oldID] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("adjudication_tasks", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.adjudications[oldID] = id
+	return true, nil
+}
+
+// restoreGoldLabel 按(physician_id, trait)匹配已有的参考标签，不存在时插入
+func (r *restorer) restoreGoldLabel(row map[string]interface{}) (bool, error) {
+	oldID := key(row["id"])
+
+	var id int
+	err := r.tx.QueryRow(`
+		SELECT id FROM gold_labels WHERE physician_id = $1 AND trait = $2
+	`, row["physician_id"], row["trait"]).Scan(&id)
+	if err == nil {
+		r.goldLabels[oldID] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("gold_labels", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.goldLabels[oldID] = id
+	return true, nil
+}
+
+// restoreQualificationAttempt 按(evaluator, started_at)匹配已有的资格测试，不存在时插入。
+// 目标库中该用户已有未完成的测试时，归档中未完成的测试及其作答跳过
+func (r *restorer) restoreQualificationAttempt(row map[string]interface{}) (bool, error) {
+	oldID := key(row["id"])
+
+	var id int
+	query, args := whereEqual([]string{"evaluator", "started_at"}, row)
+	err := r.tx.QueryRow(`SELECT id FROM qualification_attempts WHERE `+query+` ORDER BY id LIMIT 1`, args...).Scan(&id)
+	if err == nil {
+		r.attempts[oldID] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	if row["completed_at"] == nil {
+		var open bool
+		err := r.tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM qualification_attempts WHERE evaluator = $1 AND completed_at IS NULL)
+		`, row["evaluator"]).Scan(&open)
+		if err != nil {
+			return false, err
+		}
+		if open {
+			r.attempts[oldID] = 0
+			return false, nil
+		}
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("qualification_attempts", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.attempts[oldID] = id
+	return true, nil
+}
+
+// restoreAttentionCheck 按(task_id, physician_id)匹配已有的注意力检查，不存在时插入
+func (r *restorer) restoreAttentionCheck(row map[string]interface{}) (bool, error) {
+	oldID := key(row["id"])
+
+	var id int
+	err := r.tx.QueryRow(`
+		SELECT id FROM attention_checks WHERE task_id = $1 AND physician_id = $2
+	`, row["task_id"], row["physician_id"]).Scan(&id)
+	if err == nil {
+		r.attentionChecks[oldID] = id
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	delete(row, "id")
+	id, _, err = r.insert("attention_checks", row, "", true)
+	if err != nil {
+		return false, err
+	}
+	r.attentionChecks[oldID] = id
+	return true, nil
+}
+
+// exists 按指定列判断行是否已存在
+func (r *restorer) exists(name string, row map[string]interface{}, columns []string) (bool, error) {
+	query, args := whereEqual(columns, row)
+	var exists bool
+	err := r.tx.QueryRow(fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE %s)`, name, query), args...).Scan(&exists)
+	return exists, err
+}
+
+// insert 插入目标表中存在的列，归档中多出的列（如physician_npi或旧版本的列）忽略。
+// returning为true时返回新行的id
+func (r *restorer) insert(name string, row map[string]interface{}, suffix string, returning bool) (int, bool, error) {
+	columns, err := r.tableColumns(name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var names []string
+	for column := range row {
+		if columns[column] {
+			names = append(names, column)
+		}
+	}
+	sort.Strings(names)
+
+	placeholders := make([]string, len(names))
+	args := make([]interface{}, len(names))
+	for i, column := range names {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = row[column]
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) %s`,
+		name, strings.Join(names, ", "), strings.Join(placeholders, ", "), suffix)
+	if returning {
+		var id int
+		err := r.tx.QueryRow(query+` RETURNING id`, args...).Scan(&id)
+		return id, err == nil, err
+	}
+
+	result, err := r.tx.Exec(query, args...)
+	if err != nil {
+		return 0, false, err
+	}
+	affected, err := result.RowsAffected()
+	return 0, affected > 0, err
+}
+
+// tableColumns 目标库中表的列名
+func (r *restorer) tableColumns(name string) (map[string]bool, error) {
+	if columns, ok := r.columns[name]; ok {
+		return columns, nil
+	}
+
+	rows, err := r.tx.Query(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns[column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", name)
+	}
+	r.columns[name] = columns
+	return columns, nil
+}
+
+// whereEqual 生成 col1 = $1 AND col2 = $2 ...，NULL值使用IS NULL
+func whereEqual(columns []string, row map[string]interface{}) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, column := range columns {
+		if row[column] == nil {
+			conditions = append(conditions, column+" IS NULL")
+			continue
+		}
+		args = append(args, row[column])
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// key 将JSON值转换为映射表的键
+func key(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
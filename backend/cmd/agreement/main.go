@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/db"
+)
+
+func main() {
+	evaluators := flag.String("evaluators", "", "逗号分隔的评分者列表，为空表示全部")
+	specialty := flag.String("specialty", "", "按医生专科筛选")
+	from := flag.String("from", "", "起始日期（YYYY-MM-DD，包含）")
+	to := flag.String("to", "", "截止日期（YYYY-MM-DD，包含）")
+	report := flag.String("report", "human", "报告类型：human（评分者间一致性）或 model（人类与模型一致性）")
+	format := flag.String("format", "table", "输出格式：table、json 或 csv（仅model报告）")
+	flag.Parse()
+
+	filter, err := analytics.ParseFilter(*evaluators, *specialty, *from, *to)
+	if err != nil {
+		log.Fatal("Invalid filter: ", err)
+	}
+
+	// 加载环境变量
+	err = godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	switch *report {
+	case "human":
+		humanReport(filter, *format)
+	case "model":
+		modelReport(filter, *format)
+	default:
+		log.Fatalf("Unknown report %q", *report)
+	}
+}
+
+func humanReport(filter analytics.Filter, format string) {
+	report, err := analytics.InterAnnotatorAgreement(db.DB, filter)
+	if err != nil {
+		log.Fatal("Failed to compute agreement: ", err)
+	}
+
+	if format == "json" {
+		writeJSON(report)
+		return
+	}
+
+	fmt.Printf("Evaluators: %v\n\n", report.Evaluators)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRAIT\tDIMENSION\tUNITS\tAGREEMENT\tCOHEN\tFLEISS\tALPHA(ORD)")
+	for _, trait := range report.Traits {
+		for _, dimension := range analytics.Dimensions {
+			d := trait.Dimensions[dimension]
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", trait.Trait, dimension, d.Units,
+				formatFloat(d.PercentAgreement), formatFloat(d.CohenKappa),
+				formatFloat(d.FleissKappa), formatFloat(d.KrippendorffAlpha))
+		}
+	}
+	w.Flush()
+}
+
+func modelReport(filter analytics.Filter, format string) {
+	report, err := analytics.HumanModelAgreement(db.DB, filter)
+	if err != nil {
+		log.Fatal("Failed to compute model agreement: ", err)
+	}
+
+	switch format {
+	case "json":
+		writeJSON(report)
+	case "csv":
+		if err := analytics.WriteModelAgreementCSV(os.Stdout, report); err != nil {
+			log.Fatal("Failed to write report: ", err)
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODEL\tTRAIT\tDIMENSION\tUNITS\tEXACT\tWITHIN-1\tMAE\tSPEARMAN\tUNKNOWN LABELS")
+		for _, r := range report.Results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%v\n", r.Model, r.Trait, r.Dimension, r.Units,
+				formatFloat(r.ExactAgreement), formatFloat(r.WithinOne),
+				formatFloat(r.MAE), formatFloat(r.Spearman), r.UnknownLabels)
+		}
+		w.Flush()
+	}
+}
+
+func writeJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Fatal("Failed to write report: ", err)
+	}
+}
+
+func formatFloat(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", *value)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/backup"
+	"github.com/phyreview_annotator/db"
+)
+
+func main() {
+	output := flag.String("output", "", "归档文件路径，默认 phyreview-backup-<时间>.tar.gz")
+	flag.Parse()
+
+	if *output == "" {
+		*output = fmt.Sprintf("phyreview-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	// 加载环境变量
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	if err := db.CheckSchema(db.DB); err != nil {
+		log.Fatal("Schema check failed: ", err)
+	}
+
+	manifest, err := backup.ExportFile(db.DB, *output)
+	if err != nil {
+		log.Fatal("Backup failed: ", err)
+	}
+
+	log.Printf("Backup of %s (schema version %d) written to %s", manifest.Database, manifest.SchemaVersion, *output)
+	for _, table := range manifest.Tables {
+		log.Printf("- %s: %d rows", table.Name, table.Rows)
+	}
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
-	"io/ioutil"
+	"flag"
 	"log"
-	"path/filepath"
+	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/backup"
 	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/maintenance"
 )
 
 func main() {
+	yes := flag.Bool("yes", false, "确认清空数据库；仍需输入数据库名")
+	dumpDir := flag.String("dump-dir", "backups", "清空前写入备份归档的目录")
+	flag.Parse()
+
 	// 加载环境变量
 	err := godotenv.Load("../../.env")
 	if err != nil {
@@ -20,17 +26,29 @@ func main() {
 	db.InitDB()
 	defer db.CloseDB()
 
-	// 读取清空脚本
-	scriptPath := filepath.Join("..", "..", "db", "clean_database.sql")
-	scriptSQL, err := ioutil.ReadFile(scriptPath)
+	// 确认操作：-yes、非生产环境、输入数据库名
+	err = maintenance.Confirm(db.DB, *yes, "DELETE ALL DATA in", os.Stdin, os.Stderr)
+	if err != nil {
+		log.Fatal("Database cleanup aborted: ", err)
+	}
+
+	// 先导出备份归档，可用cmd/restore恢复；表结构不是最新版本时无法导出
+	if err := db.CheckSchema(db.DB); err != nil {
+		log.Fatal("Schema check failed, nothing was deleted (run cmd/migrate up first): ", err)
+	}
+	path, manifest, err := backup.ExportToDir(db.DB, *dumpDir)
 	if err != nil {
-		log.Fatal("Failed to read clean script:", err)
+		log.Fatal("Failed to back up annotation data, nothing was deleted: ", err)
+	}
+	log.Printf("Annotation data backed up to %s", path)
+	for _, table := range manifest.Tables {
+		log.Printf("- %s: %d rows", table.Name, table.Rows)
 	}
 
 	log.Println("Starting database cleanup...")
 
 	// 执行清空脚本
-	_, err = db.DB.Exec(string(scriptSQL))
+	_, err = db.DB.Exec(db.CleanSQL)
 	if err != nil {
 		log.Fatal("Failed to execute clean script:", err)
 	}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/consensus"
+	"github.com/phyreview_annotator/db"
+)
+
+func main() {
+	method := flag.String("method", consensus.MethodDawidSkene, "共识方法："+strings.Join(consensus.Methods, "、"))
+	evaluators := flag.String("evaluators", "", "逗号分隔的评分者列表，为空表示全部")
+	specialty := flag.String("specialty", "", "按医生专科筛选")
+	from := flag.String("from", "", "起始日期（YYYY-MM-DD，包含）")
+	to := flag.String("to", "", "截止日期（YYYY-MM-DD，包含）")
+	maxIterations := flag.Int("max-iterations", consensus.DefaultMaxIterations, "Dawid-Skene EM的最大迭代次数")
+	tolerance := flag.Float64("tolerance", consensus.DefaultTolerance, "Dawid-Skene EM的收敛阈值（后验概率的最大变化）")
+	dryRun := flag.Bool("dry-run", false, "只计算并输出结果，不保存新版本")
+	format := flag.String("format", "table", "输出格式：table 或 json")
+	flag.Parse()
+
+	if !consensus.IsMethod(*method) {
+		log.Fatalf("Unknown method %q", *method)
+	}
+	filter, err := analytics.ParseFilter(*evaluators, *specialty, *from, *to)
+	if err != nil {
+		log.Fatal("Invalid filter: ", err)
+	}
+
+	// 加载环境变量
+	err = godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	computation, err := consensus.Compute(db.DB, consensus.Options{
+		Method:        *method,
+		Filter:        filter,
+		MaxIterations: *maxIterations,
+		Tolerance:     *tolerance,
+	})
+	if err != nil {
+		log.Fatal("Failed to compute consensus: ", err)
+	}
+
+	runID := 0
+	if !*dryRun {
+		run, err := consensus.Save(db.DB, computation, "cli")
+		if err != nil {
+			log.Fatal("Failed to save consensus: ", err)
+		}
+		runID = run.ID
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(map[string]interface{}{
+			"run_id":      runID,
+			"method":      computation.Method,
+			"parameters":  computation.Parameters,
+			"labels":      computation.Labels,
+			"reliability": computation.Reliability,
+		})
+		if err != nil {
+			log.Fatal("Failed to write result: ", err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EVALUATOR\tTRAIT\tDIMENSION\tITEMS\tACCURACY")
+	for _, r := range computation.Reliability {
+		accuracy := "-"
+		if r.Accuracy != nil {
+			accuracy = fmt.Sprintf("%.3f", *r.Accuracy)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", r.Evaluator, r.Trait, r.Dimension, r.Items, accuracy)
+	}
+	w.Flush()
+
+	fmt.Println()
+	if len(computation.Parameters.NotConverged) > 0 {
+		fmt.Printf("EM did not converge for: %s\n", strings.Join(computation.Parameters.NotConverged, ", "))
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: computed %d consensus labels with %s, nothing saved\n", len(computation.Labels), computation.Method)
+		return
+	}
+	fmt.Printf("Saved %d consensus labels with %s as version %d\n", len(computation.Labels), computation.Method, runID)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/importer"
+)
+
+// 为已有医生导入一个新模型（或新版本）的输出。
+// 医生按NPI匹配，必须已存在；只写入model_annotations，
+// 不修改医生、评论、human_annotations和machine_annotation_evaluation，
+// 该模型已有的标注保持不变。
+func main() {
+	model := flag.String("model", "", "导入的模型，格式 provider/model，例如 openai/gpt-4.1（必填）")
+	input := flag.String("input", "-", "输入文件路径，- 表示标准输入")
+	format := flag.String("format", "", "输入格式：json（嵌套JSON/JSONL记录）、csv或parquet（model_annotations），默认按扩展名判断")
+	mappingPath := flag.String("mapping", "", "CSV列映射配置文件（JSON）")
+	workers := flag.Int("workers", 4, "并发导入的worker数量")
+	flag.IntVar(&importer.BatchSize, "batch-size", importer.BatchSize, "多行INSERT每批的行数")
+	runDate := flag.String("run-date", "", "模型的运行日期 (YYYY-MM-DD)")
+	promptVersion := flag.String("prompt-version", "", "本次模型输出使用的prompt版本")
+	reevaluate := flag.Bool("reevaluate", false, "导入后重新打开相关医生已开始的任务，让标注人评价新模型的输出")
+	dryRun := flag.Bool("dry-run", false, "校验并执行全部写入后回滚，不修改数据库")
+	flag.Parse()
+
+	provider, version, ok := strings.Cut(*model, "/")
+	if !ok || provider == "" || version == "" {
+		log.Fatal("-model is required and must look like provider/model, e.g. openai/gpt-4.1")
+	}
+	if *workers < 1 || importer.BatchSize < 1 {
+		log.Fatal("-workers and -batch-size must be positive")
+	}
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*input)) {
+		case ".csv":
+			*format = "csv"
+		case ".parquet":
+			*format = "parquet"
+		case ".json", ".jsonl":
+			*format = "json"
+		default:
+			log.Fatal("Cannot detect input format, use -format json, csv or parquet")
+		}
+	}
+
+	mapping, err := importer.LoadColumnMapping(*mappingPath)
+	if err != nil {
+		log.Fatal("Failed to load column mapping:", err)
+	}
+
+	if *runDate != "" {
+		date, err := time.Parse("2006-01-02", *runDate)
+		if err != nil {
+			log.Fatal("Invalid -run-date:", err)
+		}
+		importer.ModelRun.RunDate = &date
+	}
+	importer.ModelRun.PromptVersion = *promptVersion
+
+	// 加载环境变量
+	err = godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	if *format == "parquet" && *input == "-" {
+		log.Fatal("Parquet input cannot be read from stdin, pass a file with -input")
+	}
+
+	// 打开输入，流式读取
+	var in io.Reader = os.Stdin
+	var size int64
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			log.Fatal("Failed to open input:", err)
+		}
+		defer file.Close()
+		in = file
+		info, err := file.Stat()
+		if err != nil {
+			log.Fatal("Failed to open input:", err)
+		}
+		size = info.Size()
+	}
+
+	var source importer.Source
+	options := importer.CSVOptions{
+		Mapping:          mapping,
+		DefaultModel:     *model,
+		KeepExisting:     true,
+		OnlyDefaultModel: true,
+	}
+	switch *format {
+	case "json":
+		source, err = importer.NewModelRunJSONSource(in, provider, version)
+	case "csv":
+		source, err = importer.NewCSVSource(in, importer.EntityModelAnnotations, options)
+	case "parquet":
+		source, err = importer.NewParquetSource(in.(*os.File), size, importer.EntityModelAnnotations, options)
+	default:
+		log.Fatalf("Unknown -format %q", *format)
+	}
+	if err != nil {
+		log.Fatal("Failed to read input:", err)
+	}
+
+	if *reevaluate {
+		source = importer.QueueReevaluation(source, "import-model-run", "new model run "+*model)
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	summary := importer.Run(db.DB, source, importer.Options{Workers: *workers, DryRun: *dryRun})
+	summary.Print(*dryRun)
+	if summary.Failed > 0 {
+		db.CloseDB()
+		os.Exit(1)
+	}
+}
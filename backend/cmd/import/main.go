@@ -1,238 +1,136 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"flag"
+	"io"
 	"log"
-	"regexp"
-	"strconv"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/importer"
 )
 
-// JSON数据结构
-type PhysicianRecord struct {
-	PhyID        int64   `json:"PhyID"`
-	NPI          int64   `json:"NPI"`
-	FirstName    string  `json:"FirstName"`
-	LastName     string  `json:"LastName"`
-	Gender       string  `json:"Gender"`
-	Credential   string  `json:"Credential"`
-	Specialty    string  `json:"Specialty"`
-	PracticeZip5 float64 `json:"PracticeZip5"`
-	BusinessZip5 float64 `json:"BusinessZip5"`
-	BiographyDoc string  `json:"biography_doc"`
-	EducationDoc string  `json:"education_doc"`
-	NumReviews   float64 `json:"num_reviews"`
-	ReviewDoc    string  `json:"review_doc"`
-	DocName      string  `json:"DocName"`
-	Zipcode      string  `json:"zipcode"`
-	State        string  `json:"state"`
-	Region       string  `json:"Region"`
-
-	// AI模型输出
-	OutputGPT4_1    ModelOutputs `json:"output_openai_gpt-4.1"`
-	OutputGPT4O     ModelOutputs `json:"output_openai_gpt-4o"`
-	OutputGemini25F ModelOutputs `json:"output_gemini_gemini-2.5-flash-preview-05-20"`
-	OutputGemini20F ModelOutputs `json:"output_gemini_gemini-2.0-flash"`
-	OutputGemini25P ModelOutputs `json:"output_gemini_gemini-2.5-pro-preview-05-06"`
-	OutputClaude37  ModelOutputs `json:"output_anthropic_claude-3-7-sonnet-20250219"`
-}
-
-type ModelOutputs struct {
-	Openness          TraitAssessment `json:"Openness"`
-	Conscientiousness TraitAssessment `json:"Conscientiousness"`
-	Extraversion      TraitAssessment `json:"Extraversion"`
-	Agreeableness     TraitAssessment `json:"Agreeableness"`
-	Neuroticism       TraitAssessment `json:"Neuroticism"`
-}
-
-type TraitAssessment struct {
-	Consistency string `json:"consistency"`
-	Evidence    string `json:"evidence"`
-	Score       string `json:"score"`
-	Sufficiency string `json:"sufficiency"`
-}
-
-// 解析评论的正则表达式 - 使用测试过的工作模式
-var reviewRegex = regexp.MustCompile(`(?s)<review><meta>#(\d+).*?</meta>(.*?)</review>`)
-
 func main() {
-	// 加载环境变量
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	input := flag.String("input", "../../../database/first_10_phy_records.json", "输入文件路径，- 表示标准输入")
+	entity := flag.String("entity", importer.EntityRecords, "导入的实体：records（嵌套JSON/JSONL）、physicians、reviews、model_annotations（CSV）")
+	mappingPath := flag.String("mapping", "", "CSV列映射配置文件（JSON）")
+	defaultModel := flag.String("model", "", "model_annotations的CSV中没有provider和model列时使用的模型，格式 provider/model")
+	workers := flag.Int("workers", 4, "并发导入的worker数量")
+	flag.IntVar(&importer.BatchSize, "batch-size", importer.BatchSize, "多行INSERT每批的行数")
+	runDate := flag.String("run-date", "", "新注册模型的运行日期 (YYYY-MM-DD)")
+	promptVersion := flag.String("prompt-version", "", "本次模型输出使用的prompt版本")
+	dryRun := flag.Bool("dry-run", false, "校验并执行全部写入后回滚，不修改数据库")
+	resume := flag.Bool("resume", false, "跳过检查点文件中已导入的医生，继续上次中断的导入")
+	checkpointPath := flag.String("checkpoint", "", "检查点文件路径，默认按实体和输入文件命名，如 import.reviews.reviews.csv.checkpoint")
+	flag.Parse()
+
+	if *workers < 1 || importer.BatchSize < 1 {
+		log.Fatal("-workers and -batch-size must be positive")
 	}
 
-	// 初始化数据库连接
-	db.InitDB()
-	defer db.CloseDB()
-
-	// 读取JSON文件
-	jsonData, err := ioutil.ReadFile("../../../database/first_10_phy_records.json")
-	if err != nil {
-		log.Fatal("Failed to read JSON file:", err)
+	if *defaultModel != "" {
+		if provider, version, ok := strings.Cut(*defaultModel, "/"); !ok || provider == "" || version == "" {
+			log.Fatal("-model must look like provider/model, e.g. openai/gpt-4.1")
+		}
 	}
 
-	// 解析JSON
-	var records []PhysicianRecord
-	err = json.Unmarshal(jsonData, &records)
-	if err != nil {
-		log.Fatal("Failed to parse JSON:", err)
+	isParquet := strings.EqualFold(filepath.Ext(*input), ".parquet")
+	if isParquet && *entity == importer.EntityRecords {
+		log.Fatal("Parquet input needs -entity physicians, reviews or model_annotations; nested records are read from JSON")
 	}
 
-	log.Printf("Found %d physician records to import", len(records))
-
-	// 导入每个医生的数据
-	for i, record := range records {
-		log.Printf("Importing physician %d/%d: %s", i+1, len(records), record.DocName)
-
-		// 导入医生信息
-		physicianID := importPhysician(record)
-
-		// 导入评论
-		importReviews(physicianID, record.ReviewDoc)
-
-		// 导入AI模型标注
-		importModelAnnotations(physicianID, record)
+	mapping, err := importer.LoadColumnMapping(*mappingPath)
+	if err != nil {
+		log.Fatal("Failed to load column mapping:", err)
 	}
 
-	log.Println("Import completed successfully!")
-}
-
-func importPhysician(record PhysicianRecord) int {
-	// 从zipcode中提取zip3和zip2
-	zip3 := ""
-	zip2 := ""
-	if len(record.Zipcode) >= 3 {
-		zip3 = record.Zipcode[:3]
-	}
-	if len(record.Zipcode) >= 2 {
-		zip2 = record.Zipcode[:2]
+	if *runDate != "" {
+		date, err := time.Parse("2006-01-02", *runDate)
+		if err != nil {
+			log.Fatal("Invalid -run-date:", err)
+		}
+		importer.ModelRun.RunDate = &date
 	}
+	importer.ModelRun.PromptVersion = *promptVersion
 
-	query := `
-		INSERT INTO physicians (phy_id, npi, first_name, last_name, gender, credential, specialty, 
-								practice_zip5, business_zip5, biography_doc, education_doc, num_reviews,
-								doc_name, zip3, zip2, zipcode, state, region) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) 
-		RETURNING id`
-
-	var physicianID int
-	err := db.DB.QueryRow(
-		query,
-		record.PhyID,
-		record.NPI,
-		record.FirstName,
-		record.LastName,
-		record.Gender,
-		record.Credential,
-		record.Specialty,
-		fmt.Sprintf("%.0f", record.PracticeZip5),
-		fmt.Sprintf("%.0f", record.BusinessZip5),
-		record.BiographyDoc,
-		record.EducationDoc,
-		int(record.NumReviews),
-		record.DocName,
-		zip3,
-		zip2,
-		record.Zipcode,
-		record.State,
-		record.Region,
-	).Scan(&physicianID)
-
+	// 加载环境变量
+	err = godotenv.Load("../../.env")
 	if err != nil {
-		log.Printf("Warning: Failed to import physician %s: %v", record.DocName, err)
-		return 0
-	}
-
-	log.Printf("Imported physician with ID: %d", physicianID)
-	return physicianID
-}
-
-func importReviews(physicianID int, reviewDoc string) {
-	if physicianID == 0 {
-		return
+		log.Println("Warning: .env file not found, using environment variables")
 	}
 
-	// 使用正则表达式解析评论
-	matches := reviewRegex.FindAllStringSubmatch(reviewDoc, -1)
-
-	log.Printf("Found %d reviews to import", len(matches))
-
-	for _, match := range matches {
-		if len(match) != 3 {
-			continue
+	// 打开输入，流式读取
+	var in io.Reader = os.Stdin
+	var size int64
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			log.Fatal("Failed to open input:", err)
 		}
-
-		reviewNum := match[1]
-		content := strings.TrimSpace(match[2])
-
-		// 插入评论 - 使用简化的字段
-		query := `
-			INSERT INTO reviews (physician_id, review_index, source, date, text) 
-			VALUES ($1, $2, $3, $4, $5)`
-
-		reviewNumInt, _ := strconv.Atoi(reviewNum)
-		// 使用默认值，因为从简化匹配中无法提取日期和来源
-		_, err := db.DB.Exec(query, physicianID, reviewNumInt, "Unknown", time.Now(), content)
+		defer file.Close()
+		in = file
+		info, err := file.Stat()
 		if err != nil {
-			log.Printf("Warning: Failed to import review %s: %v", reviewNum, err)
+			log.Fatal("Failed to open input:", err)
 		}
+		size = info.Size()
 	}
-}
 
-func importModelAnnotations(physicianID int, record PhysicianRecord) {
-	if physicianID == 0 {
-		return
+	var source importer.Source
+	options := importer.CSVOptions{Mapping: mapping, DefaultModel: *defaultModel}
+	switch *entity {
+	case importer.EntityRecords:
+		source, err = importer.NewJSONSource(in)
+	case importer.EntityPhysicians, importer.EntityReviews, importer.EntityModelAnnotations:
+		if isParquet {
+			source, err = importer.NewParquetSource(in.(*os.File), size, *entity, options)
+		} else {
+			source, err = importer.NewCSVSource(in, *entity, options)
+		}
+	default:
+		log.Fatalf("Unknown -entity %q", *entity)
 	}
-
-	// 模型名称和输出的映射
-	modelData := map[string]ModelOutputs{
-		"GPT-4":                    record.OutputGPT4_1,
-		"GPT-4o":                   record.OutputGPT4O,
-		"Gemini-2.5-Flash-Preview": record.OutputGemini25F,
-		"Gemini-2.0-Flash":         record.OutputGemini20F,
-		"Gemini-2.5-Pro-Preview":   record.OutputGemini25P,
-		"Claude-3.7-Sonnet":        record.OutputClaude37,
+	if err != nil {
+		log.Fatal("Failed to read input:", err)
 	}
 
-	for modelName, outputs := range modelData {
-		// 特质数据映射
-		traits := map[string]TraitAssessment{
-			"openness":          outputs.Openness,
-			"conscientiousness": outputs.Conscientiousness,
-			"extraversion":      outputs.Extraversion,
-			"agreeableness":     outputs.Agreeableness,
-			"neuroticism":       outputs.Neuroticism,
-		}
-
-		for trait, assessment := range traits {
-			query := `
-				INSERT INTO model_annotations (physician_id, model_name, trait, score, 
-											  consistency, sufficiency, evidence) 
-				VALUES ($1, $2, $3, $4, $5, $6, $7)`
-
-			_, err := db.DB.Exec(
-				query,
-				physicianID,
-				modelName,
-				trait,
-				assessment.Score,
-				assessment.Consistency,
-				assessment.Sufficiency,
-				assessment.Evidence,
-			)
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
 
-			if err != nil {
-				log.Printf("Warning: Failed to import model annotation for %s/%s: %v", modelName, trait, err)
-			}
+	// dry-run不修改数据库，也不更新检查点
+	var checkpoint *importer.Checkpoint
+	if !*dryRun {
+		if *checkpointPath == "" {
+			*checkpointPath = defaultCheckpointPath(*entity, *input)
+		}
+		checkpoint, err = importer.OpenCheckpoint(*checkpointPath, *resume)
+		if err != nil {
+			log.Fatal("Failed to open checkpoint:", err)
+		}
+		defer checkpoint.Close()
+		if *resume {
+			log.Printf("Resuming: %d physicians already imported according to %s", checkpoint.Len(), *checkpointPath)
 		}
 	}
 
-	log.Printf("Imported model annotations for physician %d", physicianID)
+	summary := importer.Run(db.DB, source, importer.Options{Workers: *workers, DryRun: *dryRun, Checkpoint: checkpoint})
+	summary.Print(*dryRun)
+	if summary.Failed > 0 {
+		db.CloseDB()
+		os.Exit(1)
+	}
+}
+
+// defaultCheckpointPath 按实体和输入文件名生成检查点路径，避免不同导入共用同一个检查点
+func defaultCheckpointPath(entity, input string) string {
+	name := filepath.Base(input)
+	if input == "-" {
+		name = "stdin"
+	}
+	return "import." + entity + "." + name + ".checkpoint"
 }
@@ -1,15 +1,34 @@
 package main
 
 import (
-	"io/ioutil"
+	"flag"
+	"fmt"
 	"log"
-	"path/filepath"
+	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	"github.com/phyreview_annotator/db"
 )
 
+const usage = `Usage: go run . <command>
+
+Commands:
+  up          执行全部未执行的迁移
+  down [N]    回退N个版本（默认1）
+  to N        升级或回退到版本N（0表示回退全部）
+  status      显示当前版本和每个迁移的执行情况
+`
+
 func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// 加载环境变量
 	err := godotenv.Load("../../.env")
 	if err != nil {
@@ -20,18 +39,90 @@ func main() {
 	db.InitDB()
 	defer db.CloseDB()
 
-	// 读取迁移文件
-	migrationPath := filepath.Join("..", "..", "db", "migration_new_workflow.sql")
-	migrationSQL, err := ioutil.ReadFile(migrationPath)
+	current, err := db.SchemaVersion(db.DB)
 	if err != nil {
-		log.Fatal("Failed to read migration file:", err)
+		log.Fatal("Failed to read schema version:", err)
 	}
 
-	// 执行迁移
-	_, err = db.DB.Exec(string(migrationSQL))
+	switch args[0] {
+	case "up":
+		err = db.MigrateUp(db.DB, logStep)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatalf("Invalid step count %q", args[1])
+			}
+		}
+		target := current - steps
+		if target < 0 {
+			target = 0
+		}
+		err = db.MigrateTo(db.DB, target, logStep)
+	case "to":
+		if len(args) < 2 {
+			log.Fatal("Missing target version")
+		}
+		target, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatalf("Invalid target version %q", args[1])
+		}
+		err = db.MigrateTo(db.DB, target, logStep)
+	case "status":
+		printStatus(current)
+		return
+	default:
+		flag.Usage()
+		db.CloseDB()
+		os.Exit(2)
+	}
 	if err != nil {
-		log.Fatal("Failed to execute migration:", err)
+		log.Fatal("Migration failed:", err)
 	}
 
-	log.Println("Database migration completed successfully!")
+	version, err := db.SchemaVersion(db.DB)
+	if err != nil {
+		log.Fatal("Failed to read schema version:", err)
+	}
+	log.Printf("Database schema is at version %d", version)
+}
+
+// logStep 输出每个执行完成的迁移
+func logStep(m db.Migration, up bool) {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	log.Printf("Migrated %s: %04d_%s", direction, m.Version, m.Name)
+}
+
+// printStatus 列出内置迁移及其执行时间
+func printStatus(current int) {
+	migrations, err := db.Migrations()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	applied, err := db.AppliedMigrations(db.DB)
+	if err != nil {
+		log.Fatal("Failed to read applied migrations:", err)
+	}
+
+	appliedAt := map[int]string{}
+	for _, m := range applied {
+		appliedAt[m.Version] = m.AppliedAt.Format("2006-01-02 15:04:05")
+	}
+
+	fmt.Printf("Current version: %d, latest: %d\n", current, len(migrations))
+	for _, m := range migrations {
+		status, ok := appliedAt[m.Version]
+		if !ok {
+			status = "pending"
+		}
+		fmt.Printf("  %04d_%-24s %s\n", m.Version, m.Name, status)
+	}
+	for _, m := range applied {
+		if m.Version > len(migrations) {
+			fmt.Printf("  %04d_%-24s %s (unknown to this build)\n", m.Version, m.Name, appliedAt[m.Version])
+		}
+	}
 }
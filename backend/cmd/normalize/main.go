@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/scores"
+)
+
+// 重新解析model_annotations中的原始评分标签并写入规范化列，报告无法解析的标签
+func main() {
+	// 加载环境变量
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	rows, err := db.DB.Query(`
+		SELECT id, model_name, trait,
+			COALESCE(score, ''), COALESCE(consistency, ''), COALESCE(sufficiency, '')
+		FROM model_annotations ORDER BY id
+	`)
+	if err != nil {
+		log.Fatal("Failed to query model annotations:", err)
+	}
+
+	type annotation struct {
+		id                              int
+		model, trait                    string
+		score, consistency, sufficiency string
+	}
+	var annotations []annotation
+	for rows.Next() {
+		var a annotation
+		if err := rows.Scan(&a.id, &a.model, &a.trait, &a.score, &a.consistency, &a.sufficiency); err != nil {
+			log.Fatal("Failed to scan model annotation:", err)
+		}
+		annotations = append(annotations, a)
+	}
+	rows.Close()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Fatal("Failed to begin transaction:", err)
+	}
+
+	unparseable := map[string]int{}
+	for _, a := range annotations {
+		var values []interface{}
+		for _, raw := range []string{a.score, a.consistency, a.sufficiency} {
+			score, err := scores.Parse(raw)
+			if err != nil {
+				unparseable[raw]++
+			}
+			low, high, noEvidence := scores.Columns(score, err)
+			values = append(values, low, high, noEvidence)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE model_annotations SET
+				score_low = $1, score_high = $2, score_no_evidence = $3,
+				consistency_low = $4, consistency_high = $5, consistency_no_evidence = $6,
+				sufficiency_low = $7, sufficiency_high = $8, sufficiency_no_evidence = $9
+			WHERE id = $10
+		`, append(values, a.id)...)
+		if err != nil {
+			tx.Rollback()
+			log.Fatalf("Failed to update model annotation %d: %v", a.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatal("Failed to commit:", err)
+	}
+
+	log.Printf("Normalized %d model annotations", len(annotations))
+	if len(unparseable) > 0 {
+		log.Printf("Warning: %d distinct labels could not be parsed:", len(unparseable))
+		for label, count := range unparseable {
+			log.Printf("  %q (%d)", label, count)
+		}
+	}
+}
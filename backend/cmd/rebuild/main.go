@@ -1,15 +1,22 @@
 package main
 
 import (
-	"io/ioutil"
+	"flag"
 	"log"
-	"path/filepath"
+	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/auth"
+	"github.com/phyreview_annotator/backup"
 	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/maintenance"
 )
 
 func main() {
+	yes := flag.Bool("yes", false, "确认删除并重建所有表；仍需输入数据库名")
+	dumpDir := flag.String("dump-dir", "backups", "重建前写入备份归档的目录")
+	flag.Parse()
+
 	// 加载环境变量
 	err := godotenv.Load("../../.env")
 	if err != nil {
@@ -20,24 +27,61 @@ func main() {
 	db.InitDB()
 	defer db.CloseDB()
 
-	// 读取重建脚本
-	scriptPath := filepath.Join("..", "..", "db", "rebuild_database.sql")
-	scriptSQL, err := ioutil.ReadFile(scriptPath)
+	// 确认操作：-yes、非生产环境、输入数据库名
+	err = maintenance.Confirm(db.DB, *yes, "DROP ALL TABLES in", os.Stdin, os.Stderr)
 	if err != nil {
-		log.Fatal("Failed to read rebuild script:", err)
+		log.Fatal("Database rebuild aborted: ", err)
+	}
+
+	// 先导出备份归档，可用cmd/restore恢复；表结构不是最新版本时无法导出
+	if err := db.CheckSchema(db.DB); err != nil {
+		log.Fatal("Schema check failed, nothing was dropped (run cmd/migrate up first): ", err)
+	}
+	path, manifest, err := backup.ExportToDir(db.DB, *dumpDir)
+	if err != nil {
+		log.Fatal("Failed to back up annotation data, nothing was dropped: ", err)
+	}
+	log.Printf("Annotation data backed up to %s", path)
+	for _, table := range manifest.Tables {
+		log.Printf("- %s: %d rows", table.Name, table.Rows)
 	}
 
 	log.Println("Starting database rebuild...")
 
-	// 执行重建脚本
-	_, err = db.DB.Exec(string(scriptSQL))
+	// 执行重建脚本，删除所有表
+	_, err = db.DB.Exec(db.RebuildSQL)
 	if err != nil {
 		log.Fatal("Failed to execute rebuild script:", err)
 	}
 
+	// 执行全部迁移，重新创建表结构
+	err = db.MigrateUp(db.DB, func(m db.Migration, up bool) {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	})
+	if err != nil {
+		log.Fatal("Failed to apply migrations:", err)
+	}
+
+	// 插入测试数据
+	_, err = db.DB.Exec(db.TestDataSQL)
+	if err != nil {
+		log.Fatal("Failed to insert test data:", err)
+	}
+
+	// 创建测试用户
+	hash, err := auth.HashPassword("test_password")
+	if err != nil {
+		log.Fatal("Failed to hash test user password:", err)
+	}
+	_, err = db.DB.Exec("INSERT INTO users (username, password_hash) VALUES ($1, $2)", "test_user", hash)
+	if err != nil {
+		log.Fatal("Failed to create test user:", err)
+	}
+
 	log.Println("Database rebuild completed successfully!")
 	log.Println("Test data inserted:")
 	log.Println("- NPI: 1043259971")
 	log.Println("- Task ID: 1")
 	log.Println("- Username: test_user")
+	log.Println("- Password: test_password")
 }
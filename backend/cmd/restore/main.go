@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/backup"
+	"github.com/phyreview_annotator/db"
+)
+
+func main() {
+	input := flag.String("input", "", "backup生成的归档文件（必填）")
+	dryRun := flag.Bool("dry-run", false, "校验归档并执行全部写入后回滚，不修改数据库")
+	flag.Parse()
+
+	if *input == "" {
+		log.Fatal("-input is required")
+	}
+
+	// 解压并校验归档，校验失败时不连接数据库
+	file, err := os.Open(*input)
+	if err != nil {
+		log.Fatal("Failed to open archive:", err)
+	}
+	archive, err := backup.ReadArchive(file)
+	file.Close()
+	if err != nil {
+		log.Fatal("Invalid archive: ", err)
+	}
+	defer archive.Close()
+
+	manifest := archive.Manifest
+	log.Printf("Archive of %s created at %s (format version %d, schema version %d), checksums verified",
+		manifest.Database, manifest.CreatedAt.Format("2006-01-02 15:04:05"), manifest.Version, manifest.SchemaVersion)
+
+	// 加载环境变量
+	err = godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	if err := db.CheckSchema(db.DB); err != nil {
+		archive.Close()
+		log.Fatal("Schema check failed: ", err)
+	}
+
+	if latest, err := db.LatestVersion(); err == nil && manifest.SchemaVersion > latest {
+		log.Printf("Warning: archive schema version %d is newer than %d; columns unknown to this database are ignored",
+			manifest.SchemaVersion, latest)
+	}
+
+	results, err := backup.Restore(db.DB, archive, *dryRun)
+	if err != nil {
+		archive.Close()
+		log.Fatal("Restore failed, nothing was written: ", err)
+	}
+
+	if *dryRun {
+		log.Println("Dry run: all changes were rolled back")
+	}
+	for _, result := range results {
+		log.Printf("- %s: %d inserted, %d already present", result.Name, result.Inserted, result.Skipped)
+	}
+	log.Println("Restore completed successfully!")
+}
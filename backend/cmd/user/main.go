@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/joho/godotenv"
+	"github.com/phyreview_annotator/auth"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+func main() {
+	username := flag.String("username", "", "用户名")
+	password := flag.String("password", "", "密码")
+	role := flag.String("role", "", "用户角色：annotator、adjudicator、admin")
+	reset := flag.Bool("reset", false, "重置已存在用户的密码")
+	flag.Parse()
+
+	if *username == "" || (*password == "" && *role == "") {
+		log.Fatal("Usage: user -username <name> [-password <password>] [-role <role>] [-reset]")
+	}
+
+	switch *role {
+	case "", models.RoleAnnotator, models.RoleAdjudicator, models.RoleAdmin:
+	default:
+		log.Fatalf("Invalid role %q", *role)
+	}
+
+	// 加载环境变量
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Println("Warning: .env file not found, using environment variables")
+	}
+
+	// 初始化数据库连接
+	db.InitDB()
+	defer db.CloseDB()
+
+	// 仅修改已存在用户的角色
+	if *password == "" {
+		result, err := db.DB.Exec("UPDATE users SET role = $1 WHERE username = $2", *role, *username)
+		if err != nil {
+			log.Fatal("Failed to update role:", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			log.Fatalf("User %s does not exist", *username)
+		}
+		log.Printf("Role of user %s set to %s", *username, *role)
+		return
+	}
+
+	hash, err := auth.HashPassword(*password)
+	if err != nil {
+		log.Fatal("Failed to hash password:", err)
+	}
+
+	if *reset {
+		result, err := db.DB.Exec("UPDATE users SET password_hash = $1 WHERE username = $2", hash, *username)
+		if err != nil {
+			log.Fatal("Failed to reset password:", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			log.Fatalf("User %s does not exist", *username)
+		}
+		log.Printf("Password reset for user %s", *username)
+		return
+	}
+
+	if *role == "" {
+		*role = models.RoleAnnotator
+	}
+
+	_, err = db.DB.Exec("INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3)", *username, hash, *role)
+	if err != nil {
+		log.Fatal("Failed to create user:", err)
+	}
+
+	log.Printf("User %s created successfully with role %s!", *username, *role)
+}
@@ -0,0 +1,288 @@
+package consensus
+
+import (
+	"math"
+
+	"github.com/phyreview_annotator/stats"
+)
+
+// 共识计算方法
+const (
+	MethodMajority   = "majority"
+	MethodMedian     = "median"
+	MethodDawidSkene = "dawid_skene"
+)
+
+// Methods 全部共识计算方法
+var Methods = []string{MethodMajority, MethodMedian, MethodDawidSkene}
+
+// IsMethod 判断是否为支持的共识方法
+func IsMethod(method string) bool {
+	for _, m := range Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Estimate 一个条目的共识标签
+type Estimate struct {
+	Label      float64
+	Confidence float64
+}
+
+// RaterReliability 一位评分者的可靠性估计
+type RaterReliability struct {
+	Items     int
+	Accuracy  *float64
+	Confusion map[int]map[int]float64 // 真实标签 -> 评分 -> 概率，仅Dawid-Skene
+}
+
+// Result 一组条目的共识结果，Estimates与输入的条目一一对应
+type Result struct {
+	Estimates   []Estimate
+	Reliability map[string]RaterReliability
+	Iterations  int  // EM迭代次数，仅Dawid-Skene
+	Converged   bool // EM是否收敛，仅Dawid-Skene
+}
+
+// Majority 多数投票：取票数最多的评分，平票时取最接近中位数的评分（仍平则取较小者）。
+// 置信度为得票比例
+func Majority(units []stats.Unit) Result {
+	result := Result{Estimates: make([]Estimate, len(units))}
+	for i, unit := range units {
+		counts := map[int]int{}
+		values := make([]float64, 0, len(unit))
+		for _, value := range unit {
+			counts[value]++
+			values = append(values, float64(value))
+		}
+		median, _ := stats.Median(values)
+
+		best, bestCount := 0, 0
+		for value, count := range counts {
+			switch {
+			case count > bestCount:
+				best, bestCount = value, count
+			case count == bestCount:
+				d, bestD := math.Abs(float64(value)-median), math.Abs(float64(best)-median)
+				if d < bestD || (d == bestD && value < best) {
+					best = value
+				}
+			}
+		}
+		if len(unit) > 0 {
+			result.Estimates[i] = Estimate{Label: float64(best), Confidence: float64(bestCount) / float64(len(unit))}
+		}
+	}
+	result.Reliability = empiricalReliability(units, result.Estimates)
+	return result
+}
+
+// Median 中位数：偶数个评分时可能为x.5。置信度为与中位数相差不超过0.5的评分比例
+func Median(units []stats.Unit) Result {
+	result := Result{Estimates: make([]Estimate, len(units))}
+	for i, unit := range units {
+		values := make([]float64, 0, len(unit))
+		for _, value := range unit {
+			values = append(values, float64(value))
+		}
+		median, ok := stats.Median(values)
+		if !ok {
+			continue
+		}
+		close := 0
+		for _, value := range values {
+			if math.Abs(value-median) <= 0.5 {
+				close++
+			}
+		}
+		result.Estimates[i] = Estimate{Label: median, Confidence: float64(close) / float64(len(values))}
+	}
+	result.Reliability = empiricalReliability(units, result.Estimates)
+	return result
+}
+
+// empiricalReliability 多数投票和中位数方法的可靠性：评分与共识标签相差不超过0.5的比例。
+// 只统计至少有两位评分者的条目，单人条目的共识就是其本人评分
+func empiricalReliability(units []stats.Unit, estimates []Estimate) map[string]RaterReliability {
+	items := map[string]int{}
+	agree := map[string]int{}
+	for i, unit := range units {
+		if len(unit) < 2 {
+			continue
+		}
+		for rater, value := range unit {
+			items[rater]++
+			if math.Abs(float64(value)-estimates[i].Label) <= 0.5 {
+				agree[rater]++
+			}
+		}
+	}
+
+	reliability := map[string]RaterReliability{}
+	for _, rater := range stats.Raters(units) {
+		r := RaterReliability{Items: items[rater]}
+		if r.Items > 0 {
+			r.Accuracy = stats.Float(float64(agree[rater])/float64(r.Items), true)
+		}
+		reliability[rater] = r
+	}
+	return reliability
+}
+
+// dsSmoothing 混淆矩阵和先验的平滑项，避免零概率
+const dsSmoothing = 0.01
+
+// DawidSkene 用EM估计每位评分者的混淆矩阵和各条目真实标签的后验分布。
+// 以投票比例初始化，迭代到后验最大变化小于tolerance或达到maxIterations。
+// 共识标签为后验概率最大的评分，置信度为该后验概率；
+// 可靠性为评分者给出真实标签的概率 Σ_k p(k)·θ(k,k)
+func DawidSkene(units []stats.Unit, maxIterations int, tolerance float64) Result {
+	result := Result{Estimates: make([]Estimate, len(units)), Reliability: map[string]RaterReliability{}}
+
+	categories := stats.Categories(units)
+	raters := stats.Raters(units)
+	if len(categories) == 0 {
+		return result
+	}
+	index := map[int]int{}
+	for k, category := range categories {
+		index[category] = k
+	}
+	K := len(categories)
+
+	// 初始后验：投票比例
+	posterior := make([][]float64, len(units))
+	for i, unit := range units {
+		posterior[i] = make([]float64, K)
+		for _, value := range unit {
+			posterior[i][index[value]] += 1 / float64(len(unit))
+		}
+	}
+
+	prior := make([]float64, K)
+	confusion := map[string][][]float64{}
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		// M步：先验和混淆矩阵
+		for k := range prior {
+			prior[k] = dsSmoothing
+		}
+		for _, p := range posterior {
+			for k := range p {
+				prior[k] += p[k]
+			}
+		}
+		normalize(prior)
+
+		for _, rater := range raters {
+			matrix := make([][]float64, K)
+			for k := range matrix {
+				matrix[k] = make([]float64, K)
+				for l := range matrix[k] {
+					matrix[k][l] = dsSmoothing
+				}
+			}
+			for i, unit := range units {
+				value, ok := unit[rater]
+				if !ok {
+					continue
+				}
+				for k := range matrix {
+					matrix[k][index[value]] += posterior[i][k]
+				}
+			}
+			for k := range matrix {
+				normalize(matrix[k])
+			}
+			confusion[rater] = matrix
+		}
+
+		// E步：各条目真实标签的后验
+		change := 0.0
+		for i, unit := range units {
+			logp := make([]float64, K)
+			for k := range logp {
+				logp[k] = math.Log(prior[k])
+				for rater, value := range unit {
+					logp[k] += math.Log(confusion[rater][k][index[value]])
+				}
+			}
+			updated := softmax(logp)
+			for k := range updated {
+				change = math.Max(change, math.Abs(updated[k]-posterior[i][k]))
+			}
+			posterior[i] = updated
+		}
+
+		result.Iterations = iteration
+		if change < tolerance {
+			result.Converged = true
+			break
+		}
+	}
+
+	for i, unit := range units {
+		if len(unit) == 0 {
+			continue
+		}
+		best := 0
+		for k := range posterior[i] {
+			if posterior[i][k] > posterior[i][best] {
+				best = k
+			}
+		}
+		result.Estimates[i] = Estimate{Label: float64(categories[best]), Confidence: posterior[i][best]}
+	}
+
+	items := map[string]int{}
+	for _, unit := range units {
+		for rater := range unit {
+			items[rater]++
+		}
+	}
+	for _, rater := range raters {
+		matrix := confusion[rater]
+		r := RaterReliability{Items: items[rater], Confusion: map[int]map[int]float64{}}
+		accuracy := 0.0
+		for k, row := range matrix {
+			accuracy += prior[k] * row[k]
+			r.Confusion[categories[k]] = map[int]float64{}
+			for l, p := range row {
+				r.Confusion[categories[k]][categories[l]] = p
+			}
+		}
+		r.Accuracy = stats.Float(accuracy, true)
+		result.Reliability[rater] = r
+	}
+	return result
+}
+
+// normalize 将非负向量归一化为和为1
+func normalize(values []float64) {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	if sum == 0 {
+		return
+	}
+	for i := range values {
+		values[i] /= sum
+	}
+}
+
+// softmax 由对数概率计算归一化概率
+func softmax(logp []float64) []float64 {
+	max := math.Inf(-1)
+	for _, v := range logp {
+		max = math.Max(max, v)
+	}
+	p := make([]float64, len(logp))
+	for k, v := range logp {
+		p[k] = math.Exp(v - max)
+	}
+	normalize(p)
+	return p
+}
@@ -0,0 +1,177 @@
+package consensus
+
+import (
+	"math"
+	"testing"
+
+	"github.com/phyreview_annotator/stats"
+)
+
+func assertEstimates(t *testing.T, got []Estimate, want []Estimate) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d estimates, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Label != want[i].Label || math.Abs(got[i].Confidence-want[i].Confidence) > 1e-9 {
+			t.Errorf("unit %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func assertAccuracy(t *testing.T, result Result, rater string, items int, accuracy *float64) {
+	t.Helper()
+	r, ok := result.Reliability[rater]
+	if !ok {
+		t.Fatalf("no reliability for %s", rater)
+	}
+	if r.Items != items {
+		t.Errorf("%s: items = %d, want %d", rater, r.Items, items)
+	}
+	switch {
+	case accuracy == nil && r.Accuracy != nil:
+		t.Errorf("%s: accuracy = %v, want nil", rater, *r.Accuracy)
+	case accuracy != nil && (r.Accuracy == nil || math.Abs(*r.Accuracy-*accuracy) > 1e-9):
+		t.Errorf("%s: accuracy = %v, want %v", rater, r.Accuracy, *accuracy)
+	}
+}
+
+func TestMajority(t *testing.T) {
+	units := []stats.Unit{
+		{"a": 1, "b": 1, "c": 2},                 // 多数为1，得票2/3
+		{"a": 1, "b": 3},                         // 平票，与中位数2距离相同，取较小的1
+		{"a": 1, "b": 1, "c": 4, "d": 4, "e": 3}, // 1和4平票，中位数为3，取更接近的4
+		{"c": 5},                                 // 单人条目
+		{},                                       // 没有评分
+	}
+	result := Majority(units)
+
+	assertEstimates(t, result.Estimates, []Estimate{
+		{Label: 1, Confidence: 2.0 / 3},
+		{Label: 1, Confidence: 0.5},
+		{Label: 4, Confidence: 0.4},
+		{Label: 5, Confidence: 1},
+		{},
+	})
+
+	// 可靠性只看至少两人的条目：a在三个条目中与共识一致两次（第三个条目为4）
+	assertAccuracy(t, result, "a", 3, stats.Float(2.0/3, true))
+	assertAccuracy(t, result, "b", 3, stats.Float(1.0/3, true))
+	assertAccuracy(t, result, "c", 2, stats.Float(0.5, true))
+	assertAccuracy(t, result, "e", 1, stats.Float(0, true))
+	if result.Iterations != 0 || result.Converged {
+		t.Errorf("majority reports EM state: %+v", result)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	units := []stats.Unit{
+		{"a": 1, "b": 2, "c": 5}, // 中位数2，只有b相差不超过0.5
+		{"a": 2, "b": 3},         // 中位数2.5，两人都相差0.5
+		{"a": 4},                 // 单人条目
+	}
+	result := Median(units)
+
+	assertEstimates(t, result.Estimates, []Estimate{
+		{Label: 2, Confidence: 1.0 / 3},
+		{Label: 2.5, Confidence: 1},
+		{Label: 4, Confidence: 1},
+	})
+
+	// a的单人条目不计入可靠性
+	assertAccuracy(t, result, "a", 2, stats.Float(0.5, true))
+	assertAccuracy(t, result, "b", 2, stats.Float(1, true))
+	assertAccuracy(t, result, "c", 1, stats.Float(0, true))
+}
+
+func TestDawidSkeneConverges(t *testing.T) {
+	// a和b始终一致，c与他们只在一半条目上一致
+	units := []stats.Unit{
+		{"a": 1, "b": 1, "c": 2},
+		{"a": 2, "b": 2, "c": 1},
+		{"a": 1, "b": 1, "c": 1},
+		{"a": 2, "b": 2, "c": 2},
+		{"a": 1, "b": 1, "c": 2},
+		{"a": 2, "b": 2, "c": 1},
+	}
+	result := DawidSkene(units, 100, 1e-6)
+
+	if !result.Converged || result.Iterations >= 100 {
+		t.Fatalf("converged = %v after %d iterations, want convergence before the limit", result.Converged, result.Iterations)
+	}
+	for i, estimate := range result.Estimates {
+		if want := float64(units[i]["a"]); estimate.Label != want {
+			t.Errorf("unit %d: label = %v, want %v", i, estimate.Label, want)
+		}
+		if estimate.Confidence < 0.95 || estimate.Confidence > 1 {
+			t.Errorf("unit %d: confidence = %v, want in [0.95, 1]", i, estimate.Confidence)
+		}
+	}
+
+	a, c := result.Reliability["a"], result.Reliability["c"]
+	if a.Items != 6 || c.Items != 6 {
+		t.Errorf("items a = %d, c = %d, want 6", a.Items, c.Items)
+	}
+	if *a.Accuracy < 0.95 || *c.Accuracy > 0.6 {
+		t.Errorf("accuracy a = %v, c = %v, want a near 1 and c near 1/3", *a.Accuracy, *c.Accuracy)
+	}
+	for truth, row := range a.Confusion {
+		sum := 0.0
+		for _, p := range row {
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("confusion row %d of a sums to %v", truth, sum)
+		}
+	}
+}
+
+func TestDawidSkeneMaxIterations(t *testing.T) {
+	// 一位评分者、两个条目：先验始终为(0.5, 0.5)，每次迭代后验按 p' = (0.01 + p) / 1.02 缩向0.5，
+	// 远未达到容差
+	units := []stats.Unit{{"a": 1}, {"a": 2}}
+
+	p1 := (dsSmoothing + 1) / (1 + 2*dsSmoothing)
+	p2 := (dsSmoothing + p1) / (1 + 2*dsSmoothing)
+	for _, tt := range []struct {
+		maxIterations int
+		want          float64
+	}{
+		{1, p1},
+		{2, p2},
+	} {
+		result := DawidSkene(units, tt.maxIterations, 1e-9)
+		if result.Converged || result.Iterations != tt.maxIterations {
+			t.Fatalf("max %d: converged = %v after %d iterations, want stopped at the limit",
+				tt.maxIterations, result.Converged, result.Iterations)
+		}
+		assertEstimates(t, result.Estimates, []Estimate{
+			{Label: 1, Confidence: tt.want},
+			{Label: 2, Confidence: tt.want},
+		})
+	}
+}
+
+func TestDawidSkeneTie(t *testing.T) {
+	// 两位评分者在两个条目上意见相反：混淆矩阵均匀，后验为(0.5, 0.5)，
+	// 第一次迭代后验不变即收敛，平局取较小的评分
+	units := []stats.Unit{{"a": 1, "b": 2}, {"a": 2, "b": 1}}
+	result := DawidSkene(units, 10, 1e-6)
+
+	if !result.Converged || result.Iterations != 1 {
+		t.Fatalf("converged = %v after %d iterations, want convergence after 1", result.Converged, result.Iterations)
+	}
+	assertEstimates(t, result.Estimates, []Estimate{
+		{Label: 1, Confidence: 0.5},
+		{Label: 1, Confidence: 0.5},
+	})
+	assertAccuracy(t, result, "a", 2, stats.Float(0.5, true))
+}
+
+func TestDawidSkeneEmpty(t *testing.T) {
+	result := DawidSkene([]stats.Unit{{}}, 10, 1e-6)
+	if result.Iterations != 0 || len(result.Reliability) != 0 {
+		t.Errorf("empty input: %+v", result)
+	}
+	assertEstimates(t, result.Estimates, []Estimate{{}})
+}
@@ -0,0 +1,336 @@
+package consensus
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/stats"
+)
+
+// EM迭代的默认参数
+const (
+	DefaultMaxIterations = 1000
+	DefaultTolerance     = 1e-6
+)
+
+// Options 一次共识计算的选项
+type Options struct {
+	Method        string
+	Filter        analytics.Filter
+	MaxIterations int     // 仅Dawid-Skene，0表示默认值
+	Tolerance     float64 // 仅Dawid-Skene，0表示默认值
+}
+
+// Parameters 保存在consensus_runs.parameters中的计算参数
+type Parameters struct {
+	Filter        analytics.Filter `json:"filter"`
+	MaxIterations int              `json:"max_iterations,omitempty"`
+	Tolerance     float64          `json:"tolerance,omitempty"`
+	NotConverged  []string         `json:"not_converged,omitempty"` // 达到迭代上限仍未收敛的trait/维度
+}
+
+// Computation 计算结果，Save后成为一个新版本
+type Computation struct {
+	Method      string
+	Parameters  Parameters
+	Labels      []models.ConsensusLabel
+	Reliability []models.AnnotatorReliability
+}
+
+// Compute 读取符合筛选条件的人类标注（每位评分者取最新一条，不含注意力检查任务），
+// 按trait和维度分别计算共识标签和评分者可靠性
+func Compute(conn *sql.DB, options Options) (*Computation, error) {
+	if !IsMethod(options.Method) {
+		return nil, fmt.Errorf("unknown consensus method %q", options.Method)
+	}
+
+	computation := &Computation{Method: options.Method, Parameters: Parameters{Filter: options.Filter}}
+	if options.Method == MethodDawidSkene {
+		if options.MaxIterations <= 0 {
+			options.MaxIterations = DefaultMaxIterations
+		}
+		if options.Tolerance <= 0 {
+			options.Tolerance = DefaultTolerance
+		}
+		computation.Parameters.MaxIterations = options.MaxIterations
+		computation.Parameters.Tolerance = options.Tolerance
+	}
+
+	ratings, err := analytics.LoadHumanRatings(conn, options.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// trait -> 医生 -> 评分
+	byTrait := map[string]map[int][]analytics.HumanRating{}
+	for _, r := range ratings {
+		if byTrait[r.Trait] == nil {
+			byTrait[r.Trait] = map[int][]analytics.HumanRating{}
+		}
+		byTrait[r.Trait][r.PhysicianID] = append(byTrait[r.Trait][r.PhysicianID], r)
+	}
+	traits := make([]string, 0, len(byTrait))
+	for trait := range byTrait {
+		traits = append(traits, trait)
+	}
+	sort.Strings(traits)
+
+	for _, trait := range traits {
+		physicians := make([]int, 0, len(byTrait[trait]))
+		for id := range byTrait[trait] {
+			physicians = append(physicians, id)
+		}
+		sort.Ints(physicians)
+
+		for _, dimension := range analytics.Dimensions {
+			units := make([]stats.Unit, len(physicians))
+			for i, id := range physicians {
+				units[i] = stats.Unit{}
+				for _, r := range byTrait[trait][id] {
+					units[i][r.Evaluator] = r.Value(dimension)
+				}
+			}
+
+			var result Result
+			switch options.Method {
+			case MethodMajority:
+				result = Majority(units)
+			case MethodMedian:
+				result = Median(units)
+			case MethodDawidSkene:
+				result = DawidSkene(units, options.MaxIterations, options.Tolerance)
+				if !result.Converged {
+					computation.Parameters.NotConverged = append(computation.Parameters.NotConverged, trait+"/"+dimension)
+				}
+			}
+
+			for i, id := range physicians {
+				computation.Labels = append(computation.Labels, models.ConsensusLabel{
+					PhysicianID: id,
+					Trait:       trait,
+					Dimension:   dimension,
+					Label:       result.Estimates[i].Label,
+					Confidence:  result.Estimates[i].Confidence,
+					Annotators:  len(units[i]),
+				})
+			}
+			for _, evaluator := range sortedKeys(result.Reliability) {
+				r := result.Reliability[evaluator]
+				computation.Reliability = append(computation.Reliability, models.AnnotatorReliability{
+					Evaluator: evaluator,
+					Trait:     trait,
+					Dimension: dimension,
+					Items:     r.Items,
+					Accuracy:  r.Accuracy,
+					Confusion: confusionLabels(r.Confusion),
+				})
+			}
+		}
+	}
+	return computation, nil
+}
+
+// confusionLabels 将混淆矩阵的评分转换为JSON键
+func confusionLabels(confusion map[int]map[int]float64) map[string]map[string]float64 {
+	if confusion == nil {
+		return nil
+	}
+	labels := map[string]map[string]float64{}
+	for truth, row := range confusion {
+		labels[strconv.Itoa(truth)] = map[string]float64{}
+		for given, p := range row {
+			labels[strconv.Itoa(truth)][strconv.Itoa(given)] = p
+		}
+	}
+	return labels
+}
+
+// sortedKeys map的键按升序排列
+func sortedKeys(m map[string]RaterReliability) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Save 在一个事务中把计算结果保存为新版本，返回该版本的记录
+func Save(conn *sql.DB, computation *Computation, createdBy string) (models.ConsensusRun, error) {
+	run := models.ConsensusRun{Method: computation.Method, Units: len(computation.Labels), CreatedBy: createdBy}
+
+	parameters, err := json.Marshal(computation.Parameters)
+	if err != nil {
+		return run, err
+	}
+	run.Parameters = parameters
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return run, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO consensus_runs (method, parameters, units, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, run.Method, string(parameters), run.Units, run.CreatedBy).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return run, err
+	}
+
+	labelStmt, err := tx.Prepare(`
+		INSERT INTO consensus_labels (run_id, physician_id, trait, dimension, label, confidence, annotators)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`)
+	if err != nil {
+		return run, err
+	}
+	defer labelStmt.Close()
+	for i := range computation.Labels {
+		label := &computation.Labels[i]
+		label.RunID = run.ID
+		_, err := labelStmt.Exec(run.ID, label.PhysicianID, label.Trait, label.Dimension,
+			label.Label, label.Confidence, label.Annotators)
+		if err != nil {
+			return run, fmt.Errorf("save consensus label: %w", err)
+		}
+	}
+
+	reliabilityStmt, err := tx.Prepare(`
+		INSERT INTO annotator_reliability (run_id, evaluator, trait, dimension, items, accuracy, confusion)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`)
+	if err != nil {
+		return run, err
+	}
+	defer reliabilityStmt.Close()
+	for i := range computation.Reliability {
+		r := &computation.Reliability[i]
+		r.RunID = run.ID
+		var confusion interface{}
+		if r.Confusion != nil {
+			data, err := json.Marshal(r.Confusion)
+			if err != nil {
+				return run, err
+			}
+			confusion = string(data)
+		}
+		_, err := reliabilityStmt.Exec(run.ID, r.Evaluator, r.Trait, r.Dimension, r.Items, r.Accuracy, confusion)
+		if err != nil {
+			return run, fmt.Errorf("save annotator reliability: %w", err)
+		}
+	}
+
+	return run, tx.Commit()
+}
+
+const runColumns = `id, method, parameters, units, created_by, created_at`
+
+func scanRun(row db.Scanner) (models.ConsensusRun, error) {
+	var run models.ConsensusRun
+	var parameters []byte
+	err := row.Scan(&run.ID, &run.Method, &parameters, &run.Units, &run.CreatedBy, &run.CreatedAt)
+	run.Parameters = parameters
+	return run, err
+}
+
+// ListRuns 列出全部版本，最新的在前；method为空时不筛选
+func ListRuns(conn *sql.DB, method string) ([]models.ConsensusRun, error) {
+	rows, err := conn.Query(`
+		SELECT `+runColumns+` FROM consensus_runs
+		WHERE ($1 = '' OR method = $1)
+		ORDER BY id DESC
+	`, method)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []models.ConsensusRun{}
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// LoadRun 读取一个版本；id为0时读取最新版本（可按method筛选）。不存在时返回sql.ErrNoRows
+func LoadRun(conn *sql.DB, id int, method string) (models.ConsensusRun, error) {
+	return scanRun(conn.QueryRow(`
+		SELECT `+runColumns+` FROM consensus_runs
+		WHERE ($1 = 0 OR id = $1) AND ($2 = '' OR method = $2)
+		ORDER BY id DESC
+		LIMIT 1
+	`, id, method))
+}
+
+// LoadLabels 读取某版本的共识标签，physicianID为0、trait为空时不筛选
+func LoadLabels(conn *sql.DB, runID, physicianID int, trait string) ([]models.ConsensusLabel, error) {
+	rows, err := conn.Query(`
+		SELECT l.run_id, l.physician_id, p.npi, l.trait, l.dimension, l.label, l.confidence, l.annotators
+		FROM consensus_labels l JOIN physicians p ON p.id = l.physician_id
+		WHERE l.run_id = $1 AND ($2 = 0 OR l.physician_id = $2) AND ($3 = '' OR l.trait = $3)
+		ORDER BY l.physician_id, l.trait, l.dimension
+	`, runID, physicianID, trait)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := []models.ConsensusLabel{}
+	for rows.Next() {
+		var l models.ConsensusLabel
+		err := rows.Scan(&l.RunID, &l.PhysicianID, &l.NPI, &l.Trait, &l.Dimension, &l.Label, &l.Confidence, &l.Annotators)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
+
+// LoadReliability 读取某版本的评分者可靠性
+func LoadReliability(conn *sql.DB, runID int) ([]models.AnnotatorReliability, error) {
+	rows, err := conn.Query(`
+		SELECT run_id, evaluator, trait, dimension, items, accuracy, confusion
+		FROM annotator_reliability
+		WHERE run_id = $1
+		ORDER BY evaluator, trait, dimension
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reliability := []models.AnnotatorReliability{}
+	for rows.Next() {
+		var r models.AnnotatorReliability
+		var accuracy sql.NullFloat64
+		var confusion []byte
+		err := rows.Scan(&r.RunID, &r.Evaluator, &r.Trait, &r.Dimension, &r.Items, &accuracy, &confusion)
+		if err != nil {
+			return nil, err
+		}
+		if accuracy.Valid {
+			r.Accuracy = &accuracy.Float64
+		}
+		if confusion != nil {
+			if err := json.Unmarshal(confusion, &r.Confusion); err != nil {
+				return nil, err
+			}
+		}
+		reliability = append(reliability, r)
+	}
+	return reliability, rows.Err()
+}
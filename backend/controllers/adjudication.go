@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/adjudication"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
+)
+
+// adjudicationColumns 查询仲裁任务时的列，与scanAdjudicationTask对应
+const adjudicationColumns = `a.id, a.physician_id, p.npi, a.trait, a.status, COALESCE(a.assigned_to, ''),
+	a.spread, a.threshold, a.created_at, a.resolved_at`
+
+func scanAdjudicationTask(row db.Scanner) (models.AdjudicationTask, error) {
+	var task models.AdjudicationTask
+	var resolvedAt sql.NullTime
+	err := row.Scan(&task.ID, &task.PhysicianID, &task.NPI, &task.Trait, &task.Status, &task.AssignedTo,
+		&task.Spread, &task.Threshold, &task.CreatedAt, &resolvedAt)
+	if resolvedAt.Valid {
+		task.ResolvedAt = &resolvedAt.Time
+	}
+	return task, err
+}
+
+// loadAdjudicationTask 读取仲裁任务
+func loadAdjudicationTask(q db.Querier, id int) (models.AdjudicationTask, error) {
+	return scanAdjudicationTask(q.QueryRow(`
+		SELECT `+adjudicationColumns+`
+		FROM adjudication_tasks a JOIN physicians p ON p.id = a.physician_id
+		WHERE a.id = $1
+	`, id))
+}
+
+// canViewAdjudication adjudicator只能查看指派给自己或尚未指派的仲裁，admin可以查看全部
+func canViewAdjudication(c *gin.Context, task models.AdjudicationTask) bool {
+	if middleware.CurrentRole(c) == models.RoleAdmin {
+		return true
+	}
+	return task.AssignedTo == "" || task.AssignedTo == middleware.CurrentEvaluator(c)
+}
+
+// ListAdjudications 列出仲裁任务，默认只列出未完成的：?status=open|resolved|cancelled|all
+func ListAdjudications(c *gin.Context) {
+	status := c.DefaultQuery("status", models.AdjudicationOpen)
+	if status == "all" {
+		status = ""
+	}
+
+	// admin查看全部，其他人只看指派给自己的和待领取的
+	username := middleware.CurrentEvaluator(c)
+	if middleware.CurrentRole(c) == models.RoleAdmin {
+		username = ""
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT `+adjudicationColumns+`
+		FROM adjudication_tasks a JOIN physicians p ON p.id = a.physician_id
+		WHERE ($1 = '' OR a.status = $1)
+		AND ($2 = '' OR a.assigned_to IS NULL OR a.assigned_to = $2)
+		ORDER BY a.created_at, a.id
+	`, status, username)
+	if err != nil {
+		log.Println("查询仲裁任务列表错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询仲裁任务出错"})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.AdjudicationTask{}
+	for rows.Next() {
+		task, err := scanAdjudicationTask(rows)
+		if err != nil {
+			log.Println("扫描仲裁任务数据错误:", err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetAdjudication 获取仲裁任务，并列出该医生该trait的全部人类标注和模型标注供对照
+func GetAdjudication(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的仲裁任务ID"})
+		return
+	}
+
+	task, err := loadAdjudicationTask(db.DB, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "仲裁任务不存在"})
+			return
+		}
+		log.Println("查询仲裁任务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询仲裁任务出错"})
+		return
+	}
+	if !canViewAdjudication(c, task) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该仲裁任务未指派给当前用户"})
+		return
+	}
+
+	var physician models.Physician
+	err = db.DB.QueryRow(`
+		SELECT id, npi, first_name, last_name, credential, specialty
+		FROM physicians WHERE id = $1
+	`, task.PhysicianID).Scan(
+		&physician.ID, &physician.NPI, &physician.FirstName, &physician.LastName,
+		&physician.Credential, &physician.Specialty,
+	)
+	if err != nil {
+		log.Println("查询医生信息错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询医生信息出错"})
+		return
+	}
+
+	// 全部人类标注，按评分者和时间排列
+	rows, err := db.DB.Query(`
+		SELECT id, physician_id, evaluator, task_id, trait, score, consistency, sufficiency, evidence, timestamp
+		FROM human_annotations
+		WHERE physician_id = $1 AND trait = $2
+		ORDER BY evaluator, timestamp
+	`, task.PhysicianID, task.Trait)
+	if err != nil {
+		log.Println("查询人类标注错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询人类标注出错"})
+		return
+	}
+	defer rows.Close()
+
+	humanAnnotations := []models.HumanAnnotation{}
+	for rows.Next() {
+		var annotation models.HumanAnnotation
+		err := rows.Scan(
+			&annotation.ID, &annotation.PhysicianID, &annotation.Evaluator, &annotation.TaskID,
+			&annotation.Trait, &annotation.Score, &annotation.Consistency, &annotation.Sufficiency,
+			&annotation.Evidence, &annotation.Timestamp,
+		)
+		if err != nil {
+			log.Println("扫描人类标注数据错误:", err)
+			continue
+		}
+		humanAnnotations = append(humanAnnotations, annotation)
+	}
+
+	modelRows, err := db.DB.Query(`
+		SELECT `+modelAnnotationColumns+`
+		FROM model_annotations
+		WHERE physician_id = $1 AND trait = $2
+		ORDER BY model_name
+	`, task.PhysicianID, task.Trait)
+	if err != nil {
+		log.Println("查询模型标注错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询模型标注出错"})
+		return
+	}
+	defer modelRows.Close()
+
+	modelAnnotations := []models.ModelAnnotation{}
+	for modelRows.Next() {
+		annotation, err := scanModelAnnotation(modelRows)
+		if err != nil {
+			log.Println("扫描模型标注数据错误:", err)
+			continue
+		}
+		annotation.PhysicianID = task.PhysicianID
+		modelAnnotations = append(modelAnnotations, annotation)
+	}
+
+	// 已完成的仲裁附带最终标签
+	var label *models.AdjudicatedLabel
+	var l models.AdjudicatedLabel
+	err = db.DB.QueryRow(`
+		SELECT id, adjudication_id, physician_id, trait, score, consistency, sufficiency, rationale, adjudicator, timestamp
+		FROM adjudicated_labels WHERE adjudication_id = $1
+	`, task.ID).Scan(
+		&l.ID, &l.AdjudicationID, &l.PhysicianID, &l.Trait, &l.Score, &l.Consistency,
+		&l.Sufficiency, &l.Rationale, &l.Adjudicator, &l.Timestamp,
+	)
+	if err == nil {
+		label = &l
+	} else if err != sql.ErrNoRows {
+		log.Println("查询仲裁标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询仲裁标签出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"adjudication":      task,
+		"physician":         physician,
+		"human_annotations": humanAnnotations,
+		"model_annotations": modelAnnotations,
+		"label":             label,
+	})
+}
+
+// ResolveAdjudication 提交仲裁的最终标签和理由，完成仲裁任务。
+// 待领取的任务由提交者领取，但参与了该分歧的评分者不能仲裁
+func ResolveAdjudication(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的仲裁任务ID"})
+		return
+	}
+
+	var request struct {
+		Score       int    `json:"score" binding:"required,min=1,max=5"`
+		Consistency int    `json:"consistency" binding:"required,min=1,max=5"`
+		Sufficiency int    `json:"sufficiency" binding:"required,min=1,max=5"`
+		Rationale   string `json:"rationale" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rationale := strings.TrimSpace(request.Rationale)
+	if rationale == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "仲裁理由不能为空"})
+		return
+	}
+
+	username := middleware.CurrentEvaluator(c)
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	task, err := loadAdjudicationTask(tx, id)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "仲裁任务不存在"})
+			return
+		}
+		log.Println("查询仲裁任务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询仲裁任务出错"})
+		return
+	}
+	if task.Status != models.AdjudicationOpen {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "仲裁任务已结束", "status": task.Status})
+		return
+	}
+	if task.AssignedTo != "" && task.AssignedTo != username && middleware.CurrentRole(c) != models.RoleAdmin {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "该仲裁任务未指派给当前用户"})
+		return
+	}
+
+	// 参与了该分歧的评分者不能仲裁自己的标注
+	var annotated bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM human_annotations
+			WHERE physician_id = $1 AND trait = $2 AND evaluator = $3
+		)
+	`, task.PhysicianID, task.Trait, username).Scan(&annotated)
+	if err != nil {
+		tx.Rollback()
+		log.Println("检查人类标注记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查人类标注记录出错"})
+		return
+	}
+	if annotated {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能仲裁自己参与标注的分歧"})
+		return
+	}
+
+	currentTime := time.Now()
+	_, err = tx.Exec(`
+		INSERT INTO adjudicated_labels
+		(adjudication_id, physician_id, trait, score, consistency, sufficiency, rationale, adjudicator, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, task.ID, task.PhysicianID, task.Trait, request.Score, request.Consistency, request.Sufficiency,
+		rationale, username, currentTime)
+	if err != nil {
+		tx.Rollback()
+		log.Println("保存仲裁标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存仲裁标签出错"})
+		return
+	}
+
+	// 只在任务仍未完成时更新，防止并发提交
+	result, err := tx.Exec(`
+		UPDATE adjudication_tasks
+		SET status = $1, resolved_at = $2, assigned_to = COALESCE(assigned_to, $3)
+		WHERE id = $4 AND status = $5
+	`, models.AdjudicationResolved, currentTime, username, task.ID, models.AdjudicationOpen)
+	if err != nil {
+		tx.Rollback()
+		log.Println("更新仲裁任务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新仲裁任务出错"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "仲裁任务已结束"})
+		return
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "仲裁完成", "adjudication_id": task.ID})
+}
+
+// DetectAdjudications 管理员手动扫描全部已完成回顾的标注并创建仲裁任务，
+// 可在请求体中临时指定阈值和维度，默认使用环境变量配置
+func DetectAdjudications(c *gin.Context) {
+	var request struct {
+		Threshold  float64 `json:"threshold"`
+		Dimensions string  `json:"dimensions"` // 逗号分隔，如 "score,consistency"
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Threshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "阈值必须大于0"})
+		return
+	}
+
+	cfg := adjudication.LoadConfig()
+	if request.Threshold > 0 {
+		cfg.Threshold = request.Threshold
+	}
+	if request.Dimensions != "" {
+		dimensions, err := adjudication.ParseDimensions(request.Dimensions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cfg.Dimensions = dimensions
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	created, err := adjudication.Detect(tx, cfg, 0, "")
+	if err != nil {
+		tx.Rollback()
+		log.Println("检测标注分歧错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检测标注分歧出错"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "分歧检测完成", "created": created, "config": cfg})
+}
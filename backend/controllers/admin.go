@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/auth"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// isValidRole 检查角色是否合法
+func isValidRole(role string) bool {
+	switch role {
+	case models.RoleAnnotator, models.RoleAdjudicator, models.RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// ListUsers 获取所有用户及其角色
+func ListUsers(c *gin.Context) {
+	rows, err := db.DB.Query(`
+		SELECT id, username, role, created_at, qualified_at
+		FROM users ORDER BY username
+	`)
+	if err != nil {
+		log.Println("查询用户列表错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+		return
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.QualifiedAt); err != nil {
+			log.Println("扫描用户数据错误:", err)
+			continue
+		}
+		users = append(users, user)
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateUser 创建用户账号
+func CreateUser(c *gin.Context) {
+	var request struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		Role     string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Role == "" {
+		request.Role = models.RoleAnnotator
+	}
+	if !isValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色"})
+		return
+	}
+
+	hash, err := auth.HashPassword(request.Password)
+	if err != nil {
+		log.Println("生成密码哈希错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户出错"})
+		return
+	}
+
+	var user models.User
+	err = db.DB.QueryRow(`
+		INSERT INTO users (username, password_hash, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO NOTHING
+		RETURNING id, username, role, created_at, qualified_at
+	`, request.Username, hash, request.Role).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.QualifiedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
+			return
+		}
+		log.Println("创建用户错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户出错"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUserRole 修改用户角色
+func UpdateUserRole(c *gin.Context) {
+	username := c.Param("username")
+
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色"})
+		return
+	}
+
+	result, err := db.DB.Exec("UPDATE users SET role = $1 WHERE username = $2", request.Role, username)
+	if err != nil {
+		log.Println("更新用户角色错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新角色出错"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "角色更新成功"})
+}
+
+// UpdateUserQualification 管理员手动设置用户是否通过资格测试（免测或撤销）
+func UpdateUserQualification(c *gin.Context) {
+	username := c.Param("username")
+
+	var request struct {
+		Qualified *bool `json:"qualified" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var qualifiedAt interface{}
+	if *request.Qualified {
+		qualifiedAt = time.Now()
+	}
+
+	result, err := db.DB.Exec("UPDATE users SET qualified_at = $1 WHERE username = $2", qualifiedAt, username)
+	if err != nil {
+		log.Println("更新资格测试状态错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资格测试状态出错"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "资格测试状态更新成功", "qualified": *request.Qualified})
+}
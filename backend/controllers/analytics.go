@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/db"
+)
+
+// GetInterAnnotatorAgreement 计算评分者间一致性，支持按评分者、专科和日期范围筛选
+func GetInterAnnotatorAgreement(c *gin.Context) {
+	filter, err := analytics.ParseFilter(c.Query("evaluators"), c.Query("specialty"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := analytics.InterAnnotatorAgreement(db.DB, filter)
+	if err != nil {
+		log.Println("计算评分者间一致性错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算一致性出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetHumanModelAgreement 人类与模型一致性报告，format=csv时返回CSV
+func GetHumanModelAgreement(c *gin.Context) {
+	filter, err := analytics.ParseFilter(c.Query("evaluators"), c.Query("specialty"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := analytics.HumanModelAgreement(db.DB, filter)
+	if err != nil {
+		log.Println("计算人类与模型一致性错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算一致性出错"})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		c.Header("Content-Disposition", `attachment; filename="model_agreement.csv"`)
+		c.Header("Content-Type", "text/csv")
+		if err := analytics.WriteModelAgreementCSV(c.Writer, report); err != nil {
+			log.Println("输出CSV错误:", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetScoreMappings 获取模型标签映射表
+func GetScoreMappings(c *gin.Context) {
+	mappings, err := analytics.LoadLabelMappings(db.DB)
+	if err != nil {
+		log.Println("查询标签映射错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询标签映射出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// UpdateScoreMappings 新增或修改模型标签映射，value为null表示不参与比较
+func UpdateScoreMappings(c *gin.Context) {
+	var mappings []analytics.LabelMapping
+	if err := c.ShouldBindJSON(&mappings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, mapping := range mappings {
+		if !isValidDimension(mapping.Dimension) || mapping.Label == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的映射", "mapping": mapping})
+			return
+		}
+		if mapping.Value != nil && (*mapping.Value < 1 || *mapping.Value > 5) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "映射值必须在1到5之间", "mapping": mapping})
+			return
+		}
+	}
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	for _, mapping := range mappings {
+		_, err := tx.Exec(`
+			INSERT INTO score_label_mappings (dimension, label, value)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (dimension, label) DO UPDATE SET value = EXCLUDED.value
+		`, mapping.Dimension, mapping.Label, mapping.Value)
+		if err != nil {
+			tx.Rollback()
+			log.Println("保存标签映射错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标签映射出错"})
+			return
+		}
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "标签映射保存成功"})
+}
+
+// isValidDimension 检查标注维度是否合法
+func isValidDimension(dimension string) bool {
+	for _, d := range analytics.Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/attentioncheck"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
+)
+
+// recordAttentionCheck 标注阶段的提交属于注意力检查任务时记录结果，
+// 评分者近期准确率低于阈值时生成告警。失败时回滚事务并直接写入错误响应
+func recordAttentionCheck(c *gin.Context, tx *sql.Tx, annotation models.HumanAnnotation, stage string, timestamp time.Time) bool {
+	if stage != models.StageHumanAnnotation {
+		return true
+	}
+
+	recorded, err := attentioncheck.Record(tx, annotation, timestamp)
+	if err == nil && recorded {
+		var alert *models.AttentionCheckAlert
+		alert, err = attentioncheck.Evaluate(tx, attentioncheck.LoadConfig(), annotation.Evaluator, timestamp)
+		if alert != nil {
+			log.Printf("Warning: attention check accuracy of %s dropped to %.2f over the last %d results",
+				alert.Evaluator, alert.Accuracy, alert.Results)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		log.Println("记录注意力检查结果错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标注数据出错"})
+		return false
+	}
+	return true
+}
+
+// GetAttentionChecks 按评分者汇总注意力检查结果，可按?evaluator=筛选
+func GetAttentionChecks(c *gin.Context) {
+	cfg := attentioncheck.LoadConfig()
+	summaries, err := attentioncheck.Summarize(db.DB, cfg, c.Query("evaluator"))
+	if err != nil {
+		log.Println("查询注意力检查结果错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询注意力检查结果出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"config": cfg, "evaluators": summaries})
+}
+
+// ListAttentionCheckAlerts 列出注意力检查告警，默认只列出未处理的：?status=open|all
+func ListAttentionCheckAlerts(c *gin.Context) {
+	alerts, err := attentioncheck.ListAlerts(db.DB, c.Query("status") == "all")
+	if err != nil {
+		log.Println("查询注意力检查告警错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询注意力检查告警出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// AcknowledgeAttentionCheckAlert 标记告警已处理，之后准确率仍低于阈值时会生成新告警
+func AcknowledgeAttentionCheckAlert(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的告警ID"})
+		return
+	}
+
+	err = attentioncheck.Acknowledge(db.DB, id, middleware.CurrentEvaluator(c), time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "告警不存在或已处理"})
+			return
+		}
+		log.Println("处理注意力检查告警错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理注意力检查告警出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "告警已处理"})
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/auth"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
+)
+
+// Login 校验用户名密码并签发令牌
+func Login(c *gin.Context) {
+	var request struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	err := db.DB.QueryRow(`
+		SELECT id, username, password_hash, role, created_at, qualified_at
+		FROM users WHERE username = $1
+	`, request.Username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.QualifiedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+			return
+		}
+		log.Println("查询用户错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+		return
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, request.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		return
+	}
+
+	token, expiresAt, err := auth.IssueToken(user.Username)
+	if err != nil {
+		log.Println("签发令牌错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签发令牌出错"})
+		return
+	}
+
+	_, err = db.DB.Exec("UPDATE users SET last_login = $1 WHERE id = $2", time.Now(), user.ID)
+	if err != nil {
+		log.Println("更新登录时间错误:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user":       user,
+	})
+}
+
+// GetCurrentUser 获取当前登录用户信息
+func GetCurrentUser(c *gin.Context) {
+	var user models.User
+	err := db.DB.QueryRow(`
+		SELECT id, username, role, created_at, qualified_at
+		FROM users WHERE username = $1
+	`, middleware.CurrentEvaluator(c)).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.QualifiedAt)
+	if err != nil {
+		log.Println("查询用户错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/consensus"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+)
+
+// CreateConsensusRun 计算共识标签和评分者可靠性并保存为新版本
+func CreateConsensusRun(c *gin.Context) {
+	var request struct {
+		Method        string  `json:"method" binding:"required"` // majority, median, dawid_skene
+		Evaluators    string  `json:"evaluators"`                // 逗号分隔，为空表示全部
+		Specialty     string  `json:"specialty"`
+		From          string  `json:"from"`
+		To            string  `json:"to"`
+		MaxIterations int     `json:"max_iterations"`
+		Tolerance     float64 `json:"tolerance"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !consensus.IsMethod(request.Method) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的共识方法，可选majority、median或dawid_skene"})
+		return
+	}
+
+	filter, err := analytics.ParseFilter(request.Evaluators, request.Specialty, request.From, request.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	computation, err := consensus.Compute(db.DB, consensus.Options{
+		Method:        request.Method,
+		Filter:        filter,
+		MaxIterations: request.MaxIterations,
+		Tolerance:     request.Tolerance,
+	})
+	if err != nil {
+		log.Println("计算共识标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算共识标签出错"})
+		return
+	}
+
+	run, err := consensus.Save(db.DB, computation, middleware.CurrentEvaluator(c))
+	if err != nil {
+		log.Println("保存共识标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存共识标签出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"run": run, "reliability": computation.Reliability})
+}
+
+// ListConsensusRuns 列出共识标签的全部版本，可按?method=筛选
+func ListConsensusRuns(c *gin.Context) {
+	runs, err := consensus.ListRuns(db.DB, c.Query("method"))
+	if err != nil {
+		log.Println("查询共识版本错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询共识版本出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetConsensusRun 获取一个版本的共识标签和评分者可靠性。
+// id为latest时取最新版本（可按?method=筛选），标签可按?npi=和?trait=筛选
+func GetConsensusRun(c *gin.Context) {
+	id := 0
+	if c.Param("id") != "latest" {
+		var err error
+		id, err = strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的版本ID"})
+			return
+		}
+	}
+
+	physicianID := 0
+	if npiStr := c.Query("npi"); npiStr != "" {
+		npi, err := strconv.ParseInt(npiStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的NPI号码"})
+			return
+		}
+		err = db.DB.QueryRow("SELECT id FROM physicians WHERE npi = $1", npi).Scan(&physicianID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未找到该医生信息"})
+			return
+		}
+	}
+
+	run, err := consensus.LoadRun(db.DB, id, c.Query("method"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "共识版本不存在"})
+			return
+		}
+		log.Println("查询共识版本错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询共识版本出错"})
+		return
+	}
+
+	labels, err := consensus.LoadLabels(db.DB, run.ID, physicianID, c.Query("trait"))
+	if err != nil {
+		log.Println("查询共识标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询共识标签出错"})
+		return
+	}
+
+	reliability, err := consensus.LoadReliability(db.DB, run.ID)
+	if err != nil {
+		log.Println("查询评分者可靠性错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询评分者可靠性出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"run": run, "labels": labels, "reliability": reliability})
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/evidence"
+	"github.com/phyreview_annotator/models"
+)
+
+// validateCitations 按评论原文校验证据引用并补全，失败时回滚事务并直接写入错误响应
+func validateCitations(c *gin.Context, tx *sql.Tx, physicianID int, citations []models.EvidenceCitation) bool {
+	err := evidence.Validate(tx, physicianID, citations)
+	if err == nil {
+		return true
+	}
+
+	tx.Rollback()
+	var invalid *evidence.ValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "证据引用与评论原文不符", "detail": err.Error()})
+		return false
+	}
+	log.Println("校验证据引用错误:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "校验证据引用出错"})
+	return false
+}
+
+// attachModelCitations 为模型标注填入引用的评论片段
+func attachModelCitations(annotations []models.ModelAnnotation) error {
+	ids := make([]int, len(annotations))
+	for i, annotation := range annotations {
+		ids[i] = annotation.ID
+	}
+	citations, err := evidence.LoadModel(db.DB, ids)
+	if err != nil {
+		return err
+	}
+	for i := range annotations {
+		annotations[i].Citations = citations[annotations[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/modelregistry"
+)
+
+// ListModels 列出模型注册表
+func ListModels(c *gin.Context) {
+	list, err := modelregistry.List(db.DB)
+	if err != nil {
+		log.Println("查询模型注册表错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询模型出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateModel 修改模型的显示名称和运行日期
+func UpdateModel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的模型ID"})
+		return
+	}
+
+	var request struct {
+		DisplayName string `json:"display_name" binding:"required"`
+		RunDate     string `json:"run_date"` // YYYY-MM-DD，留空表示未知
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	displayName := strings.TrimSpace(request.DisplayName)
+	if displayName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "显示名称不能为空"})
+		return
+	}
+
+	var runDate *time.Time
+	if request.RunDate != "" {
+		date, err := time.Parse("2006-01-02", request.RunDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的run_date，格式应为YYYY-MM-DD"})
+			return
+		}
+		runDate = &date
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	model, err := modelregistry.Update(tx, id, displayName, runDate)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "模型不存在"})
+			return
+		}
+		log.Println("更新模型错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新模型出错"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model)
+}
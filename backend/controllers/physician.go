@@ -2,14 +2,22 @@ package controllers
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/adjudication"
 	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/evidence"
+	"github.com/phyreview_annotator/middleware"
 	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/qualification"
+	"github.com/phyreview_annotator/scores"
+	"github.com/phyreview_annotator/workflow"
 )
 
 // GetPhysicianByNPI 根据NPI号码获取医生信息
@@ -47,12 +55,46 @@ func GetPhysicianByNPI(c *gin.Context) {
 		return
 	}
 
-	// 查询医生的评论
-	rows, err := db.DB.Query(`
+	// 查询医生的评论，可按来源和日期筛选：?source=Vitals&from=2010-01-01&to=2012-12-31&sort=date
+	query := `
 		SELECT id, physician_id, review_index, source, date, text
-		FROM reviews WHERE physician_id = $1
-		ORDER BY review_index
-	`, physician.ID)
+		FROM reviews WHERE physician_id = $1`
+	args := []interface{}{physician.ID}
+
+	if source := c.Query("source"); source != "" {
+		args = append(args, source)
+		query += fmt.Sprintf(" AND source = $%d", len(args))
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的from日期，格式应为YYYY-MM-DD"})
+			return
+		}
+		args = append(args, date)
+		query += fmt.Sprintf(" AND date >= $%d", len(args))
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的to日期，格式应为YYYY-MM-DD"})
+			return
+		}
+		args = append(args, date.AddDate(0, 0, 1))
+		query += fmt.Sprintf(" AND date < $%d", len(args))
+	}
+
+	switch c.DefaultQuery("sort", "index") {
+	case "index":
+		query += " ORDER BY review_index"
+	case "date":
+		query += " ORDER BY date, review_index"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排序方式，可选index或date"})
+		return
+	}
+
+	rows, err := db.DB.Query(query, args...)
 	if err != nil {
 		log.Println("查询评论错误:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询评论出错"})
@@ -108,12 +150,7 @@ func GetPhysicianByNPI(c *gin.Context) {
 func GetPhysicianTask(c *gin.Context) {
 	npiStr := c.Param("npi")
 	taskIDStr := c.Param("taskID")
-	username := c.Query("username")
-
-	if username == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户名参数"})
-		return
-	}
+	username := middleware.CurrentEvaluator(c)
 
 	npi, err := strconv.ParseInt(npiStr, 10, 64)
 	if err != nil {
@@ -141,52 +178,65 @@ func GetPhysicianTask(c *gin.Context) {
 	}
 
 	// 查询任务信息
-	var task models.Task
-	err = db.DB.QueryRow(`
-		SELECT id, physician_id, status, assigned_to
-		FROM tasks WHERE id = $1 AND physician_id = $2
-	`, taskID, physicianID).Scan(
-		&task.ID, &task.PhysicianID, &task.Status, &task.AssignedTo,
-	)
-
+	task, err := loadTask(taskID, physicianID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// 如果任务不存在，创建新任务
-			_, err = db.DB.Exec(`
-				INSERT INTO tasks (id, physician_id, status, assigned_to)
-				VALUES ($1, $2, 'pending', $3)
-			`, taskID, physicianID, username)
-			if err != nil {
-				log.Println("创建任务错误:", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务出错"})
-				return
-			}
-			task = models.Task{
-				ID:          taskID,
-				PhysicianID: physicianID,
-				Status:      "pending",
-				AssignedTo:  username,
-			}
-		} else {
+		if err != sql.ErrNoRows {
 			log.Println("查询任务错误:", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务出错"})
 			return
 		}
-	} else if task.AssignedTo != username {
-		// 更新任务指派人
-		_, err = db.DB.Exec("UPDATE tasks SET assigned_to = $1 WHERE id = $2", username, taskID)
+		if !autoCreateTasks() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		required, err := qualification.Required(db.DB, username)
 		if err != nil {
-			log.Println("更新任务指派人错误:", err)
+			log.Println("查询资格测试状态错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试状态出错"})
+			return
 		}
-		task.AssignedTo = username
+		if required {
+			c.JSON(http.StatusForbidden, gin.H{"error": "用户尚未通过资格测试", "qualification_required": true})
+			return
+		}
+
+		// 项目允许自动创建任务时，创建并指派给当前用户
+		_, err = db.DB.Exec(`
+			INSERT INTO tasks (id, physician_id, status, assigned_to)
+			VALUES ($1, $2, $3, $4)
+		`, taskID, physicianID, models.TaskStatusPending, username)
+		if err != nil {
+			log.Println("创建任务错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务出错"})
+			return
+		}
+		task = models.Task{
+			ID:          taskID,
+			PhysicianID: physicianID,
+			NPI:         npi,
+			Status:      models.TaskStatusPending,
+			AssignedTo:  username,
+			Timestamp:   time.Now(),
+		}
+	} else if task.AssignedTo != username && middleware.CurrentRole(c) != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该任务未指派给当前用户"})
+		return
+	}
+
+	// 盲标：只返回当前用户已完成人类标注的trait的模型标注
+	unlockedTraits, err := workflow.UnlockedTraits(db.DB, physicianID, taskID, username)
+	if err != nil {
+		log.Println("查询已完成人类标注的trait错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询进度出错"})
+		return
 	}
 
 	// 查询模型标注
 	rows, err := db.DB.Query(`
-		SELECT id, model_name, trait, score, consistency, sufficiency, evidence
+		SELECT `+modelAnnotationColumns+`
 		FROM model_annotations
-		WHERE physician_id = $1
-	`, physicianID)
+		WHERE physician_id = $1 AND trait = ANY($2)
+	`, physicianID, pq.Array(unlockedTraits))
 	if err != nil {
 		log.Println("查询模型标注错误:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询模型标注出错"})
@@ -196,12 +246,7 @@ func GetPhysicianTask(c *gin.Context) {
 
 	modelAnnotations := []models.ModelAnnotation{}
 	for rows.Next() {
-		var annotation models.ModelAnnotation
-		err := rows.Scan(
-			&annotation.ID, &annotation.ModelName, &annotation.Trait,
-			&annotation.Score, &annotation.Consistency, &annotation.Sufficiency,
-			&annotation.Evidence,
-		)
+		annotation, err := scanModelAnnotation(rows)
 		if err != nil {
 			log.Println("扫描模型标注数据错误:", err)
 			continue
@@ -210,9 +255,24 @@ func GetPhysicianTask(c *gin.Context) {
 		modelAnnotations = append(modelAnnotations, annotation)
 	}
 
+	// 证据引用：模型标注的引用，以及当前用户各trait人类标注当前值的引用，用于在评论中高亮
+	if err := attachModelCitations(modelAnnotations); err != nil {
+		log.Println("查询模型标注证据引用错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证据引用出错"})
+		return
+	}
+	humanCitations, err := evidence.LoadCurrentHuman(db.DB, physicianID, taskID, username)
+	if err != nil {
+		log.Println("查询人类标注证据引用错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证据引用出错"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"task":              task,
 		"model_annotations": modelAnnotations,
+		"unlocked_traits":   unlockedTraits,
+		"human_citations":   humanCitations,
 	})
 }
 
@@ -229,6 +289,15 @@ func SubmitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
+	evaluator := middleware.CurrentEvaluator(c)
+
+	// 确认所有标注涉及的任务都已指派给当前用户
+	for _, annotation := range annotations {
+		if _, ok := authorizeTask(c, annotation.TaskID, annotation.PhysicianID); !ok {
+			return
+		}
+	}
+
 	// 开始事务
 	tx, err := db.DB.Begin()
 	if err != nil {
@@ -237,7 +306,26 @@ func SubmitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
-	for _, annotation := range annotations {
+	// 人类标注只能在标注阶段提交，或在回顾修改阶段修改
+	stages := make([]string, len(annotations))
+	for i, annotation := range annotations {
+		stage, ok := requireStage(c, tx, annotation.PhysicianID, annotation.TaskID, evaluator, annotation.Trait,
+			models.StageHumanAnnotation, models.StageReviewAndModify)
+		if !ok {
+			return
+		}
+		stages[i] = stage
+
+		// 证据引用必须与该医生的评论原文一致
+		if !validateCitations(c, tx, annotation.PhysicianID, annotation.Citations) {
+			return
+		}
+	}
+
+	now := time.Now()
+	for i, annotation := range annotations {
+		annotation.Evaluator = evaluator
+
 		// 插入或更新标注
 		_, err := tx.Exec(`
 			INSERT INTO human_annotations 
@@ -253,7 +341,7 @@ func SubmitHumanAnnotation(c *gin.Context) {
 		`,
 			annotation.PhysicianID, annotation.Evaluator, annotation.TaskID,
 			annotation.Trait, annotation.Score, annotation.Consistency,
-			annotation.Sufficiency, annotation.Evidence, time.Now())
+			annotation.Sufficiency, annotation.Evidence, now)
 
 		if err != nil {
 			tx.Rollback()
@@ -261,6 +349,32 @@ func SubmitHumanAnnotation(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标注数据出错"})
 			return
 		}
+
+		// 保留每次提交的修订记录及其证据引用
+		if err := recordHumanRevision(tx, annotation, stages[i], now); err != nil {
+			tx.Rollback()
+			log.Println("插入标注修订记录错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标注数据出错"})
+			return
+		}
+		if err := evidence.SaveHuman(tx, annotation); err != nil {
+			tx.Rollback()
+			log.Println("保存证据引用错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标注数据出错"})
+			return
+		}
+
+		// 注意力检查任务记录标注阶段的结果
+		if !recordAttentionCheck(c, tx, annotation, stages[i], now) {
+			return
+		}
+	}
+
+	// 推进任务状态
+	for _, annotation := range annotations {
+		if !advanceTask(c, tx, annotation.TaskID, annotation.PhysicianID, evaluator) {
+			return
+		}
 	}
 
 	// 提交事务
@@ -279,12 +393,7 @@ func GetTraitProgress(c *gin.Context) {
 	npiStr := c.Param("npi")
 	taskIDStr := c.Param("taskID")
 	trait := c.Param("trait")
-	username := c.Query("username")
-
-	if username == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户名参数"})
-		return
-	}
+	username := middleware.CurrentEvaluator(c)
 
 	npi, err := strconv.ParseInt(npiStr, 10, 64)
 	if err != nil {
@@ -306,6 +415,11 @@ func GetTraitProgress(c *gin.Context) {
 		return
 	}
 
+	// 确认任务已指派给当前用户
+	if _, ok := authorizeTask(c, taskID, physicianID); !ok {
+		return
+	}
+
 	// 查询trait进度
 	var progress models.TraitProgress
 	err = db.DB.QueryRow(`
@@ -483,6 +597,9 @@ func GetTraitProgress(c *gin.Context) {
 		}
 	}
 
+	progress.Stage = workflow.StageFromFlags(
+		progress.HumanAnnotationCompleted, progress.MachineEvaluationCompleted, progress.ReviewCompleted)
+
 	c.JSON(http.StatusOK, progress)
 }
 
@@ -512,11 +629,17 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
+	// 确认任务已指派给当前用户
+	if _, ok := authorizeTask(c, taskID, physicianID); !ok {
+		return
+	}
+
 	var annotation models.HumanAnnotation
 	if err := c.ShouldBindJSON(&annotation); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	annotation.Evaluator = middleware.CurrentEvaluator(c)
 
 	// 开始事务
 	tx, err := db.DB.Begin()
@@ -526,6 +649,22 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
+	// 人类标注只能在标注阶段提交，或在回顾修改阶段修改
+	stage, ok := requireStage(c, tx, physicianID, taskID, annotation.Evaluator, trait,
+		models.StageHumanAnnotation, models.StageReviewAndModify)
+	if !ok {
+		return
+	}
+	annotation.PhysicianID = physicianID
+	annotation.TaskID = taskID
+	annotation.Trait = trait
+	currentTime := time.Now()
+
+	// 证据引用必须与该医生的评论原文一致
+	if !validateCitations(c, tx, physicianID, annotation.Citations) {
+		return
+	}
+
 	// 插入或更新人类标注
 	_, err = tx.Exec(`
 		INSERT INTO human_annotations 
@@ -541,7 +680,7 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 	`,
 		physicianID, annotation.Evaluator, taskID, trait,
 		annotation.Score, annotation.Consistency, annotation.Sufficiency,
-		annotation.Evidence, time.Now())
+		annotation.Evidence, currentTime)
 
 	if err != nil {
 		tx.Rollback()
@@ -550,6 +689,25 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
+	// 保留每次提交的修订记录，回顾阶段的修改不会覆盖最初的判断
+	if err := recordHumanRevision(tx, annotation, stage, currentTime); err != nil {
+		tx.Rollback()
+		log.Println("插入标注修订记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标注数据出错"})
+		return
+	}
+	if err := evidence.SaveHuman(tx, annotation); err != nil {
+		tx.Rollback()
+		log.Println("保存证据引用错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存标注数据出错"})
+		return
+	}
+
+	// 注意力检查任务记录标注阶段的结果
+	if !recordAttentionCheck(c, tx, annotation, stage, currentTime) {
+		return
+	}
+
 	// 首先检查progress记录是否存在
 	var progressExists bool
 	err = tx.QueryRow(`
@@ -566,8 +724,6 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
-	currentTime := time.Now()
-
 	if progressExists {
 		// 如果记录存在，更新它
 		_, err = tx.Exec(`
@@ -591,6 +747,11 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 		return
 	}
 
+	// 推进任务状态
+	if !advanceTask(c, tx, taskID, physicianID, annotation.Evaluator) {
+		return
+	}
+
 	// 提交事务
 	err = tx.Commit()
 	if err != nil {
@@ -605,6 +766,7 @@ func SubmitTraitHumanAnnotation(c *gin.Context) {
 // GetTraitMachineAnnotations 获取指定trait的所有机器标注
 func GetTraitMachineAnnotations(c *gin.Context) {
 	npiStr := c.Param("npi")
+	taskIDStr := c.Param("taskID")
 	trait := c.Param("trait")
 
 	npi, err := strconv.ParseInt(npiStr, 10, 64)
@@ -613,6 +775,12 @@ func GetTraitMachineAnnotations(c *gin.Context) {
 		return
 	}
 
+	taskID, err := strconv.Atoi(taskIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
 	// 获取医生ID
 	var physicianID int
 	err = db.DB.QueryRow("SELECT id FROM physicians WHERE npi = $1", npi).Scan(&physicianID)
@@ -621,9 +789,26 @@ func GetTraitMachineAnnotations(c *gin.Context) {
 		return
 	}
 
+	// 确认任务已指派给当前用户
+	if _, ok := authorizeTask(c, taskID, physicianID); !ok {
+		return
+	}
+
+	// 盲标：人类标注完成前不返回机器标注
+	stage, err := workflow.CurrentStage(db.DB, physicianID, taskID, middleware.CurrentEvaluator(c), trait)
+	if err != nil {
+		log.Println("查询工作流阶段错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询工作流阶段出错"})
+		return
+	}
+	if stage == models.StageHumanAnnotation {
+		c.JSON(http.StatusForbidden, gin.H{"error": "完成人类标注后才能查看机器标注", "current_stage": stage})
+		return
+	}
+
 	// 查询指定trait的机器标注
 	rows, err := db.DB.Query(`
-		SELECT id, model_name, trait, score, consistency, sufficiency, evidence
+		SELECT `+modelAnnotationColumns+`
 		FROM model_annotations
 		WHERE physician_id = $1 AND trait = $2
 		ORDER BY model_name
@@ -640,12 +825,7 @@ func GetTraitMachineAnnotations(c *gin.Context) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var annotation models.ModelAnnotation
-		err := rows.Scan(
-			&annotation.ID, &annotation.ModelName, &annotation.Trait,
-			&annotation.Score, &annotation.Consistency, &annotation.Sufficiency,
-			&annotation.Evidence,
-		)
+		annotation, err := scanModelAnnotation(rows)
 		if err != nil {
 			log.Println("扫描机器标注数据错误:", err)
 			continue
@@ -654,9 +834,49 @@ func GetTraitMachineAnnotations(c *gin.Context) {
 		annotations = append(annotations, annotation)
 	}
 
+	if err := attachModelCitations(annotations); err != nil {
+		log.Println("查询模型标注证据引用错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证据引用出错"})
+		return
+	}
+
 	c.JSON(http.StatusOK, annotations)
 }
 
+// modelAnnotationColumns 查询模型标注时的列，与scanModelAnnotation对应
+const modelAnnotationColumns = `id, model_id, model_name, trait, score, consistency, sufficiency, evidence,
+	score_low, score_high, score_no_evidence,
+	consistency_low, consistency_high, consistency_no_evidence,
+	sufficiency_low, sufficiency_high, sufficiency_no_evidence`
+
+// scanModelAnnotation 扫描一行模型标注，包括原始标签和规范化结果
+func scanModelAnnotation(rows *sql.Rows) (models.ModelAnnotation, error) {
+	var annotation models.ModelAnnotation
+	var modelID sql.NullInt64
+	var low, high [3]sql.NullFloat64
+	var noEvidence [3]sql.NullBool
+	err := rows.Scan(
+		&annotation.ID, &modelID, &annotation.ModelName, &annotation.Trait,
+		&annotation.Score, &annotation.Consistency, &annotation.Sufficiency,
+		&annotation.Evidence,
+		&low[0], &high[0], &noEvidence[0],
+		&low[1], &high[1], &noEvidence[1],
+		&low[2], &high[2], &noEvidence[2],
+	)
+	if err != nil {
+		return annotation, err
+	}
+
+	if modelID.Valid {
+		id := int(modelID.Int64)
+		annotation.ModelID = &id
+	}
+	annotation.ScoreNormalized = scores.FromColumns(low[0], high[0], noEvidence[0])
+	annotation.ConsistencyNormalized = scores.FromColumns(low[1], high[1], noEvidence[1])
+	annotation.SufficiencyNormalized = scores.FromColumns(low[2], high[2], noEvidence[2])
+	return annotation, nil
+}
+
 // SubmitMachineAnnotationEvaluation 提交对机器标注的评价
 func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 	npiStr := c.Param("npi")
@@ -683,12 +903,27 @@ func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 		return
 	}
 
+	// 确认任务已指派给当前用户
+	if _, ok := authorizeTask(c, taskID, physicianID); !ok {
+		return
+	}
+
 	var evaluations []models.MachineAnnotationEvaluation
 	if err := c.ShouldBindJSON(&evaluations); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if len(evaluations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有提供评价数据"})
+		return
+	}
+
+	evaluator := middleware.CurrentEvaluator(c)
+	for i := range evaluations {
+		evaluations[i].Evaluator = evaluator
+	}
+
 	// 开始事务
 	tx, err := db.DB.Begin()
 	if err != nil {
@@ -697,7 +932,18 @@ func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 		return
 	}
 
+	// 机器标注评价必须在人类标注完成之后提交
+	stage, ok := requireStage(c, tx, physicianID, taskID, evaluator, trait, models.StageMachineEvaluation)
+	if !ok {
+		return
+	}
+
+	currentTime := time.Now()
 	for _, evaluation := range evaluations {
+		evaluation.PhysicianID = physicianID
+		evaluation.TaskID = taskID
+		evaluation.Trait = trait
+
 		// 插入或更新机器标注评价
 		_, err := tx.Exec(`
 			INSERT INTO machine_annotation_evaluation
@@ -710,7 +956,7 @@ func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 			timestamp = EXCLUDED.timestamp
 		`,
 			evaluation.ModelAnnotationID, physicianID, taskID, evaluation.Evaluator,
-			trait, evaluation.ModelName, evaluation.Rating, evaluation.Comment, time.Now())
+			trait, evaluation.ModelName, evaluation.Rating, evaluation.Comment, currentTime)
 
 		if err != nil {
 			tx.Rollback()
@@ -718,6 +964,14 @@ func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存评价数据出错"})
 			return
 		}
+
+		// 保留每次提交的修订记录
+		if err := recordMachineRevision(tx, evaluation, stage, currentTime); err != nil {
+			tx.Rollback()
+			log.Println("插入机器标注评价修订记录错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存评价数据出错"})
+			return
+		}
 	}
 
 	// 检查progress记录是否存在
@@ -736,8 +990,6 @@ func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 		return
 	}
 
-	currentTime := time.Now()
-
 	if progressExists {
 		// 如果记录存在，更新它
 		_, err = tx.Exec(`
@@ -777,6 +1029,11 @@ func SubmitMachineAnnotationEvaluation(c *gin.Context) {
 		return
 	}
 
+	// 推进任务状态
+	if !advanceTask(c, tx, taskID, physicianID, evaluator) {
+		return
+	}
+
 	// 提交事务
 	err = tx.Commit()
 	if err != nil {
@@ -793,12 +1050,7 @@ func GetTraitHistory(c *gin.Context) {
 	npiStr := c.Param("npi")
 	taskIDStr := c.Param("taskID")
 	trait := c.Param("trait")
-	username := c.Query("username")
-
-	if username == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少用户名参数"})
-		return
-	}
+	username := middleware.CurrentEvaluator(c)
 
 	npi, err := strconv.ParseInt(npiStr, 10, 64)
 	if err != nil {
@@ -820,6 +1072,11 @@ func GetTraitHistory(c *gin.Context) {
 		return
 	}
 
+	// 确认任务已指派给当前用户
+	if _, ok := authorizeTask(c, taskID, physicianID); !ok {
+		return
+	}
+
 	// 查询人类标注历史
 	var humanAnnotation models.HumanAnnotation
 	err = db.DB.QueryRow(`
@@ -870,8 +1127,40 @@ func GetTraitHistory(c *gin.Context) {
 		evaluations = append(evaluations, evaluation)
 	}
 
+	// 查询完整修订链，保留回顾阶段修改前的原始判断
+	humanRevisions, err := loadHumanRevisions(physicianID, taskID, username, trait)
+	if err != nil {
+		log.Println("查询人类标注修订记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询历史数据出错"})
+		return
+	}
+
+	machineRevisions, err := loadMachineRevisions(physicianID, taskID, username, trait)
+	if err != nil {
+		log.Println("查询机器标注评价修订记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询评价历史出错"})
+		return
+	}
+
+	// 每次修订引用的评论片段，当前值使用最新修订的引用
+	citations, err := evidence.LoadHuman(db.DB, physicianID, taskID, username, trait)
+	if err != nil {
+		log.Println("查询人类标注证据引用错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询历史数据出错"})
+		return
+	}
+	for i := range humanRevisions {
+		humanRevisions[i].Citations = citations[humanRevisions[i].Revision]
+	}
+	if n := len(humanRevisions); n > 0 {
+		humanAnnotation.Citations = citations[humanRevisions[n-1].Revision]
+	}
+
 	result := gin.H{
-		"machine_evaluations": evaluations,
+		"machine_evaluations":          evaluations,
+		"human_annotation_revisions":   humanRevisions,
+		"machine_evaluation_revisions": machineRevisions,
+		"changed_after_review":         changedAfterReview(humanRevisions),
 	}
 
 	if hasHumanAnnotation {
@@ -907,6 +1196,11 @@ func CompleteTraitReview(c *gin.Context) {
 		return
 	}
 
+	// 确认任务已指派给当前用户
+	if _, ok := authorizeTask(c, taskID, physicianID); !ok {
+		return
+	}
+
 	var requestData struct {
 		Evaluator string `json:"evaluator"`
 		Comment   string `json:"comment,omitempty"`
@@ -915,6 +1209,7 @@ func CompleteTraitReview(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	requestData.Evaluator = middleware.CurrentEvaluator(c)
 
 	// 开始事务
 	tx, err := db.DB.Begin()
@@ -924,6 +1219,11 @@ func CompleteTraitReview(c *gin.Context) {
 		return
 	}
 
+	// 只有完成机器标注评价后才能完成回顾
+	if _, ok := requireStage(c, tx, physicianID, taskID, requestData.Evaluator, trait, models.StageReviewAndModify); !ok {
+		return
+	}
+
 	// 检查progress记录是否存在
 	var progressExists bool
 	err = tx.QueryRow(`
@@ -998,6 +1298,19 @@ func CompleteTraitReview(c *gin.Context) {
 		return
 	}
 
+	// 与其他评分者的分歧超过阈值时创建仲裁任务
+	if _, err := adjudication.Detect(tx, adjudication.LoadConfig(), physicianID, trait); err != nil {
+		tx.Rollback()
+		log.Println("检测标注分歧错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检测标注分歧出错"})
+		return
+	}
+
+	// 推进任务状态，全部trait完成时任务标记为completed
+	if !advanceTask(c, tx, taskID, physicianID, requestData.Evaluator) {
+		return
+	}
+
 	// 提交事务
 	err = tx.Commit()
 	if err != nil {
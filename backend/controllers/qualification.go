@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/qualification"
+)
+
+// GetQualificationStatus 当前用户的资格测试状态：是否需要测试、进行中的测试和历史记录
+func GetQualificationStatus(c *gin.Context) {
+	username := middleware.CurrentEvaluator(c)
+
+	required, err := qualification.Required(db.DB, username)
+	if err != nil {
+		log.Println("查询资格测试状态错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试状态出错"})
+		return
+	}
+
+	var qualifiedAt *time.Time
+	err = db.DB.QueryRow("SELECT qualified_at FROM users WHERE username = $1", username).Scan(&qualifiedAt)
+	if err != nil {
+		log.Println("查询用户错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+		return
+	}
+
+	attempts, err := qualification.ListAttempts(db.DB, username)
+	if err != nil {
+		log.Println("查询资格测试记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试记录出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"required":     required,
+		"qualified_at": qualifiedAt,
+		"thresholds":   qualification.LoadConfig(),
+		"attempts":     attempts,
+	})
+}
+
+// GetNextQualificationItem 返回资格测试的下一道题，没有进行中的测试时开始新测试。
+// 题目只包含医生和trait，不返回参考标签；医生信息和评论通过 /physician/{npi} 获取
+func GetNextQualificationItem(c *gin.Context) {
+	username := middleware.CurrentEvaluator(c)
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	required, err := qualification.Required(tx, username)
+	if err != nil {
+		tx.Rollback()
+		log.Println("查询资格测试状态错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试状态出错"})
+		return
+	}
+	if !required {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "无需参加资格测试"})
+		return
+	}
+
+	attempt, err := qualification.OpenAttempt(tx, username)
+	if err == sql.ErrNoRows {
+		attempt, err = qualification.StartAttempt(tx, username)
+	}
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, qualification.ErrNoItems) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "没有资格测试题目"})
+			return
+		}
+		log.Println("开始资格测试错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "开始资格测试出错"})
+		return
+	}
+
+	item, err := qualification.NextItem(tx, attempt.ID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusConflict, gin.H{"error": "资格测试的题目已全部作答"})
+			return
+		}
+		log.Println("查询资格测试题目错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试题目出错"})
+		return
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attempt": attempt, "item": item})
+}
+
+// SubmitQualificationAnswer 提交资格测试一道题的评分；最后一道题提交后自动评分并结束测试
+func SubmitQualificationAnswer(c *gin.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的题目ID"})
+		return
+	}
+
+	var request struct {
+		Score       int `json:"score" binding:"required,min=1,max=5"`
+		Consistency int `json:"consistency" binding:"required,min=1,max=5"`
+		Sufficiency int `json:"sufficiency" binding:"required,min=1,max=5"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := middleware.CurrentEvaluator(c)
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	attempt, err := qualification.OpenAttempt(tx, username)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusConflict, gin.H{"error": "没有进行中的资格测试"})
+			return
+		}
+		log.Println("查询资格测试错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试出错"})
+		return
+	}
+
+	label := qualification.Label{Score: request.Score, Consistency: request.Consistency, Sufficiency: request.Sufficiency}
+	if err := qualification.SubmitAnswer(tx, attempt.ID, itemID, label); err != nil {
+		tx.Rollback()
+		if errors.Is(err, qualification.ErrItemNotInAttempt) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "题目不属于当前资格测试"})
+			return
+		}
+		log.Println("保存资格测试作答错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存作答出错"})
+		return
+	}
+
+	result, err := qualification.Finish(tx, attempt, qualification.LoadConfig())
+	if err != nil {
+		tx.Rollback()
+		log.Println("资格测试评分错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "资格测试评分出错"})
+		return
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	if result == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "作答已保存", "completed": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "资格测试已完成", "completed": true, "result": result})
+}
+
+// ListGoldLabels 列出全部参考标签
+func ListGoldLabels(c *gin.Context) {
+	rows, err := db.DB.Query(`
+		SELECT g.id, g.physician_id, p.npi, g.trait, g.score, g.consistency, g.sufficiency,
+			g.qualification, g.attention_check, g.created_by, g.created_at
+		FROM gold_labels g JOIN physicians p ON p.id = g.physician_id
+		ORDER BY g.id
+	`)
+	if err != nil {
+		log.Println("查询参考标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询参考标签出错"})
+		return
+	}
+	defer rows.Close()
+
+	labels := []models.GoldLabel{}
+	for rows.Next() {
+		var label models.GoldLabel
+		err := rows.Scan(&label.ID, &label.PhysicianID, &label.NPI, &label.Trait, &label.Score,
+			&label.Consistency, &label.Sufficiency, &label.Qualification, &label.AttentionCheck, &label.CreatedBy, &label.CreatedAt)
+		if err != nil {
+			log.Println("扫描参考标签数据错误:", err)
+			continue
+		}
+		labels = append(labels, label)
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+// GoldLabelRequest 新增或修改参考标签时的单条记录
+type GoldLabelRequest struct {
+	NPI            int64  `json:"npi" binding:"required"`
+	Trait          string `json:"trait" binding:"required"`
+	Score          int    `json:"score" binding:"required,min=1,max=5"`
+	Consistency    int    `json:"consistency" binding:"required,min=1,max=5"`
+	Sufficiency    int    `json:"sufficiency" binding:"required,min=1,max=5"`
+	Qualification  *bool  `json:"qualification"`   // 是否用于资格测试，默认true
+	AttentionCheck bool   `json:"attention_check"` // 是否用于注意力检查，默认false
+}
+
+// UpsertGoldLabels 新增或修改参考标签，同一医生同一trait只有一条
+func UpsertGoldLabels(c *gin.Context) {
+	var request []GoldLabelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有提供参考标签"})
+		return
+	}
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	for _, item := range request {
+		if !models.IsValidTrait(item.Trait) {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的trait", "trait": item.Trait})
+			return
+		}
+
+		var physicianID int
+		err := tx.QueryRow("SELECT id FROM physicians WHERE npi = $1", item.NPI).Scan(&physicianID)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "未找到该医生信息", "npi": item.NPI})
+				return
+			}
+			log.Println("查询医生ID错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+			return
+		}
+
+		useForQualification := item.Qualification == nil || *item.Qualification
+		_, err = tx.Exec(`
+			INSERT INTO gold_labels
+			(physician_id, trait, score, consistency, sufficiency, qualification, attention_check, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (physician_id, trait) DO UPDATE SET
+				score = EXCLUDED.score, consistency = EXCLUDED.consistency,
+				sufficiency = EXCLUDED.sufficiency, qualification = EXCLUDED.qualification,
+				attention_check = EXCLUDED.attention_check
+		`, physicianID, item.Trait, item.Score, item.Consistency, item.Sufficiency,
+			useForQualification, item.AttentionCheck, middleware.CurrentEvaluator(c))
+		if err != nil {
+			tx.Rollback()
+			log.Println("保存参考标签错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存参考标签出错"})
+			return
+		}
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "参考标签保存成功", "saved": len(request)})
+}
+
+// DeleteGoldLabel 删除参考标签，进行中的资格测试中的该题一并删除
+func DeleteGoldLabel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的参考标签ID"})
+		return
+	}
+
+	result, err := db.DB.Exec("DELETE FROM gold_labels WHERE id = $1", id)
+	if err != nil {
+		log.Println("删除参考标签错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除参考标签出错"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "参考标签不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "参考标签已删除"})
+}
+
+// ListQualificationAttempts 列出资格测试记录，可按?evaluator=筛选
+func ListQualificationAttempts(c *gin.Context) {
+	attempts, err := qualification.ListAttempts(db.DB, c.Query("evaluator"))
+	if err != nil {
+		log.Println("查询资格测试记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试记录出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// requireQualified 确认用户已通过资格测试，未通过时回滚事务并直接写入错误响应
+func requireQualified(c *gin.Context, tx *sql.Tx, username string) bool {
+	required, err := qualification.Required(tx, username)
+	if err != nil {
+		tx.Rollback()
+		log.Println("查询资格测试状态错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询资格测试状态出错"})
+		return false
+	}
+	if required {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "用户尚未通过资格测试", "username": username, "qualification_required": true})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/attentioncheck"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
+)
+
+// 任务队列分配策略
+const (
+	QueuePolicyFIFO           = "fifo"
+	QueuePolicyRandom         = "random"
+	QueuePolicyLeastAnnotated = "least_annotated"
+)
+
+// queuePolicyOrder 各策略对应的排序子句
+var queuePolicyOrder = map[string]string{
+	QueuePolicyFIFO:   "t.timestamp, t.id, t.physician_id",
+	QueuePolicyRandom: "random()",
+	QueuePolicyLeastAnnotated: `(SELECT COUNT(DISTINCT h.evaluator) FROM human_annotations h
+		WHERE h.physician_id = t.physician_id), t.timestamp, t.id, t.physician_id`,
+}
+
+// queueConfig 任务队列配置，来自环境变量
+type queueConfig struct {
+	Policy  string        // TASK_QUEUE_POLICY: fifo, random, least_annotated
+	Overlap int           // TASK_QUEUE_OVERLAP: 每位医生的目标标注人数，0表示不限
+	Lease   time.Duration // TASK_LEASE_DURATION: 任务租约时长
+}
+
+func loadQueueConfig() queueConfig {
+	cfg := queueConfig{
+		Policy: QueuePolicyFIFO,
+		Lease:  30 * time.Minute,
+	}
+
+	if policy := os.Getenv("TASK_QUEUE_POLICY"); policy != "" {
+		if _, ok := queuePolicyOrder[policy]; ok {
+			cfg.Policy = policy
+		} else {
+			log.Printf("Warning: unknown TASK_QUEUE_POLICY %q, using %s", policy, cfg.Policy)
+		}
+	}
+	if overlap, err := strconv.Atoi(os.Getenv("TASK_QUEUE_OVERLAP")); err == nil && overlap >= 0 {
+		cfg.Overlap = overlap
+	}
+	if lease, err := time.ParseDuration(os.Getenv("TASK_LEASE_DURATION")); err == nil && lease > 0 {
+		cfg.Lease = lease
+	}
+	return cfg
+}
+
+// GetNextTask 为当前用户选取下一个待处理任务并加租约。
+// 优先返回自己持有租约的任务，其次是指派给自己的任务，最后从未指派的任务池中领取。
+// 从任务池领取时跳过自己已参与的医生，并遵守每位医生的目标标注人数。
+// 需要资格测试的用户先通过测试才能领取。
+// 开启注意力检查时，按比例先为用户插入一个指派给本人的检查任务，返回内容与普通任务相同。
+func GetNextTask(c *gin.Context) {
+	evaluator := middleware.CurrentEvaluator(c)
+	cfg := loadQueueConfig()
+	now := time.Now()
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	// 未通过资格测试的用户不能领取正式任务
+	if !requireQualified(c, tx, evaluator) {
+		return
+	}
+
+	// 按比例插入注意力检查任务，之后和指派给本人的任务一样被选中
+	if _, err := attentioncheck.Inject(tx, attentioncheck.LoadConfig(), evaluator, now); err != nil {
+		tx.Rollback()
+		log.Println("插入注意力检查任务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务出错"})
+		return
+	}
+
+	var task models.Task
+	err = tx.QueryRow(`
+		SELECT t.id, t.physician_id, p.npi, t.status
+		FROM tasks t JOIN physicians p ON p.id = t.physician_id
+		WHERE t.status IN ($4, $5, $6)
+		AND (t.lease_owner IS NULL OR t.lease_owner = $1 OR t.lease_expires_at < $2)
+		AND (
+			t.assigned_to = $1
+			OR (
+				(t.assigned_to IS NULL OR (
+					t.pool_claim AND t.status = $4 AND t.lease_expires_at < $2
+					AND NOT EXISTS (
+						SELECT 1 FROM human_annotations h
+						WHERE h.physician_id = t.physician_id AND h.task_id = t.id
+					)
+				))
+				AND NOT EXISTS (
+					SELECT 1 FROM tasks o
+					WHERE o.physician_id = t.physician_id AND o.assigned_to = $1
+				)
+				AND ($3 = 0 OR (
+					SELECT COUNT(DISTINCT o.assigned_to) FROM tasks o
+					WHERE o.physician_id = t.physician_id AND o.id <> t.id AND o.assigned_to IS NOT NULL
+				) < $3)
+			)
+		)
+		ORDER BY COALESCE(t.lease_owner = $1 AND t.lease_expires_at > $2, FALSE) DESC,
+			COALESCE(t.assigned_to = $1, FALSE) DESC,
+			`+queuePolicyOrder[cfg.Policy]+`
+		LIMIT 1
+		FOR UPDATE OF t SKIP LOCKED
+	`, evaluator, now, cfg.Overlap, models.TaskStatusPending, models.TaskStatusInProgress, models.TaskStatusReopened).Scan(
+		&task.ID, &task.PhysicianID, &task.NPI, &task.Status,
+	)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "没有待处理的任务"})
+			return
+		}
+		log.Println("查询下一个任务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务出错"})
+		return
+	}
+
+	// 加租约并指派给当前用户
+	leaseExpiresAt := now.Add(cfg.Lease)
+	_, err = tx.Exec(`
+		UPDATE tasks SET
+			pool_claim = CASE WHEN COALESCE(assigned_to, '') = $1 THEN pool_claim ELSE TRUE END,
+			assigned_to = $1, lease_owner = $1, lease_expires_at = $2
+		WHERE id = $3 AND physician_id = $4
+	`, evaluator, leaseExpiresAt, task.ID, task.PhysicianID)
+	if err != nil {
+		tx.Rollback()
+		log.Println("更新任务租约错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "领取任务出错"})
+		return
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":          task.ID,
+		"npi":              task.NPI,
+		"physician_id":     task.PhysicianID,
+		"status":           task.Status,
+		"lease_expires_at": leaseExpiresAt,
+		"policy":           cfg.Policy,
+	})
+}
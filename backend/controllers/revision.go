@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// recordHumanRevision 追加一条人类标注修订记录，修订号在同一标注内递增
+func recordHumanRevision(tx *sql.Tx, annotation models.HumanAnnotation, stage string, timestamp time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO human_annotation_revisions
+		(physician_id, task_id, evaluator, trait, revision, stage, score, consistency, sufficiency, evidence, timestamp)
+		SELECT $1, $2, $3, $4, COALESCE(MAX(revision), 0) + 1, $5, $6, $7, $8, $9, $10
+		FROM human_annotation_revisions
+		WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+	`, annotation.PhysicianID, annotation.TaskID, annotation.Evaluator, annotation.Trait, stage,
+		annotation.Score, annotation.Consistency, annotation.Sufficiency, annotation.Evidence, timestamp)
+	return err
+}
+
+// recordMachineRevision 追加一条机器标注评价修订记录
+func recordMachineRevision(tx *sql.Tx, evaluation models.MachineAnnotationEvaluation, stage string, timestamp time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO machine_evaluation_revisions
+		(model_annotation_id, physician_id, task_id, evaluator, trait, model_name, revision, stage, rating, comment, timestamp)
+		SELECT $1, $2, $3, $4, $5, $6, COALESCE(MAX(revision), 0) + 1, $7, $8, $9, $10
+		FROM machine_evaluation_revisions
+		WHERE model_annotation_id = $1 AND task_id = $3 AND evaluator = $4
+	`, evaluation.ModelAnnotationID, evaluation.PhysicianID, evaluation.TaskID, evaluation.Evaluator,
+		evaluation.Trait, evaluation.ModelName, stage, evaluation.Rating, evaluation.Comment, timestamp)
+	return err
+}
+
+// loadHumanRevisions 按修订号顺序读取某个trait的人类标注修订链
+func loadHumanRevisions(physicianID, taskID int, evaluator, trait string) ([]models.HumanAnnotationRevision, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, physician_id, task_id, evaluator, trait, revision, stage,
+		       score, consistency, sufficiency, evidence, timestamp
+		FROM human_annotation_revisions
+		WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+		ORDER BY revision
+	`, physicianID, taskID, evaluator, trait)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.HumanAnnotationRevision{}
+	for rows.Next() {
+		var r models.HumanAnnotationRevision
+		var stage sql.NullString
+		err := rows.Scan(
+			&r.ID, &r.PhysicianID, &r.TaskID, &r.Evaluator, &r.Trait, &r.Revision, &stage,
+			&r.Score, &r.Consistency, &r.Sufficiency, &r.Evidence, &r.Timestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		r.Stage = stage.String
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// loadMachineRevisions 按模型标注和修订号顺序读取某个trait的评价修订链
+func loadMachineRevisions(physicianID, taskID int, evaluator, trait string) ([]models.MachineEvaluationRevision, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, model_annotation_id, physician_id, task_id, evaluator, trait, model_name,
+		       revision, stage, rating, comment, timestamp
+		FROM machine_evaluation_revisions
+		WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+		ORDER BY model_annotation_id, revision
+	`, physicianID, taskID, evaluator, trait)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.MachineEvaluationRevision{}
+	for rows.Next() {
+		var r models.MachineEvaluationRevision
+		var stage sql.NullString
+		err := rows.Scan(
+			&r.ID, &r.ModelAnnotationID, &r.PhysicianID, &r.TaskID, &r.Evaluator, &r.Trait,
+			&r.ModelName, &r.Revision, &stage, &r.Rating, &r.Comment, &r.Timestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+		r.Stage = stage.String
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// changedAfterReview 判断标注者在看过模型标注后是否修改了最初的判断
+func changedAfterReview(revisions []models.HumanAnnotationRevision) bool {
+	var initial, revised *models.HumanAnnotationRevision
+	for i := range revisions {
+		switch revisions[i].Stage {
+		case models.StageHumanAnnotation:
+			initial = &revisions[i]
+		case models.StageReviewAndModify:
+			revised = &revisions[i]
+		}
+	}
+	if initial == nil || revised == nil {
+		return false
+	}
+	return initial.Score != revised.Score ||
+		initial.Consistency != revised.Consistency ||
+		initial.Sufficiency != revised.Sufficiency
+}
@@ -0,0 +1,412 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/workflow"
+)
+
+// autoCreateTasks 是否允许在打开不存在的任务时自动创建（TASK_AUTO_CREATE=true）
+func autoCreateTasks() bool {
+	return os.Getenv("TASK_AUTO_CREATE") == "true"
+}
+
+// loadTask 查询任务信息
+func loadTask(taskID, physicianID int) (models.Task, error) {
+	var task models.Task
+	err := db.DB.QueryRow(`
+		SELECT t.id, t.physician_id, p.npi, t.status, COALESCE(t.assigned_to, ''), t.timestamp
+		FROM tasks t JOIN physicians p ON p.id = t.physician_id
+		WHERE t.id = $1 AND t.physician_id = $2
+	`, taskID, physicianID).Scan(
+		&task.ID, &task.PhysicianID, &task.NPI, &task.Status, &task.AssignedTo, &task.Timestamp,
+	)
+	return task, err
+}
+
+// authorizeTask 确认任务存在且当前用户是指派人（管理员除外），失败时直接写入错误响应
+func authorizeTask(c *gin.Context, taskID, physicianID int) (models.Task, bool) {
+	task, err := loadTask(taskID, physicianID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return task, false
+		}
+		log.Println("查询任务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务出错"})
+		return task, false
+	}
+
+	if task.AssignedTo != middleware.CurrentEvaluator(c) && middleware.CurrentRole(c) != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该任务未指派给当前用户"})
+		return task, false
+	}
+
+	return task, true
+}
+
+// advanceTask 推进任务状态，失败时回滚事务并直接写入错误响应
+func advanceTask(c *gin.Context, tx *sql.Tx, taskID, physicianID int, evaluator string) bool {
+	err := workflow.AdvanceTask(tx, taskID, physicianID, evaluator)
+	if err == nil {
+		return true
+	}
+
+	tx.Rollback()
+	switch {
+	case errors.Is(err, workflow.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "任务已结束，无法继续提交", "detail": err.Error()})
+	case errors.Is(err, workflow.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	default:
+		log.Println("更新任务状态错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务状态出错"})
+	}
+	return false
+}
+
+// requireStage 确认当前工作流阶段允许本次提交并返回该阶段，失败时回滚事务并直接写入错误响应
+func requireStage(c *gin.Context, tx *sql.Tx, physicianID, taskID int, evaluator, trait string, allowed ...string) (string, bool) {
+	if !models.IsValidTrait(trait) {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的trait"})
+		return "", false
+	}
+
+	stage, err := workflow.CurrentStage(tx, physicianID, taskID, evaluator, trait)
+	if err != nil {
+		tx.Rollback()
+		log.Println("查询工作流阶段错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询工作流阶段出错"})
+		return "", false
+	}
+
+	for _, s := range allowed {
+		if stage == s {
+			return stage, true
+		}
+	}
+
+	tx.Rollback()
+	c.JSON(http.StatusConflict, gin.H{
+		"error":          "当前阶段不允许该操作",
+		"trait":          trait,
+		"current_stage":  stage,
+		"allowed_stages": allowed,
+	})
+	return stage, false
+}
+
+// TaskAssignment 批量创建或指派任务时的单条记录
+type TaskAssignment struct {
+	TaskID     int    `json:"task_id" binding:"required"`
+	NPI        int64  `json:"npi" binding:"required"`
+	AssignedTo string `json:"assigned_to"`
+}
+
+// userExists 检查用户是否存在
+func userExists(tx *sql.Tx, username string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", username).Scan(&exists)
+	return exists, err
+}
+
+// CreateTasks 批量创建任务，已存在的任务跳过
+func CreateTasks(c *gin.Context) {
+	var request struct {
+		Tasks []TaskAssignment `json:"tasks" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(request.Tasks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有提供任务数据"})
+		return
+	}
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	created, skipped := 0, 0
+	for _, item := range request.Tasks {
+		var physicianID int
+		err := tx.QueryRow("SELECT id FROM physicians WHERE npi = $1", item.NPI).Scan(&physicianID)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "未找到该医生信息", "npi": item.NPI})
+				return
+			}
+			log.Println("查询医生ID错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+			return
+		}
+
+		if item.AssignedTo != "" {
+			exists, err := userExists(tx, item.AssignedTo)
+			if err != nil {
+				tx.Rollback()
+				log.Println("查询用户错误:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+				return
+			}
+			if !exists {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "指派的用户不存在", "assigned_to": item.AssignedTo})
+				return
+			}
+			if !requireQualified(c, tx, item.AssignedTo) {
+				return
+			}
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO tasks (id, physician_id, status, assigned_to, timestamp)
+			SELECT $1, $2, $3, NULLIF($4, ''), $5
+			WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND physician_id = $2)
+		`, item.TaskID, physicianID, models.TaskStatusPending, item.AssignedTo, time.Now())
+		if err != nil {
+			tx.Rollback()
+			log.Println("创建任务错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务出错"})
+			return
+		}
+
+		if n, _ := result.RowsAffected(); n > 0 {
+			created++
+		} else {
+			skipped++
+		}
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"created": created, "skipped": skipped})
+}
+
+// AssignTasks 将已存在的任务指派给指定用户（assigned_to为空表示取消指派）
+func AssignTasks(c *gin.Context) {
+	var request struct {
+		Tasks []TaskAssignment `json:"tasks" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	for _, item := range request.Tasks {
+		if item.AssignedTo != "" {
+			exists, err := userExists(tx, item.AssignedTo)
+			if err != nil {
+				tx.Rollback()
+				log.Println("查询用户错误:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+				return
+			}
+			if !exists {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "指派的用户不存在", "assigned_to": item.AssignedTo})
+				return
+			}
+			if !requireQualified(c, tx, item.AssignedTo) {
+				return
+			}
+		}
+
+		result, err := tx.Exec(`
+			UPDATE tasks SET assigned_to = NULLIF($1, ''), timestamp = $2
+			WHERE id = $3 AND physician_id = (SELECT id FROM physicians WHERE npi = $4)
+		`, item.AssignedTo, time.Now(), item.TaskID, item.NPI)
+		if err != nil {
+			tx.Rollback()
+			log.Println("指派任务错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "指派任务出错"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			tx.Rollback()
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在", "task_id": item.TaskID, "npi": item.NPI})
+			return
+		}
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务指派成功", "assigned": len(request.Tasks)})
+}
+
+// ListTasks 按状态和指派人筛选任务
+func ListTasks(c *gin.Context) {
+	rows, err := db.DB.Query(`
+		SELECT t.id, t.physician_id, p.npi, t.status, COALESCE(t.assigned_to, ''), t.timestamp
+		FROM tasks t JOIN physicians p ON p.id = t.physician_id
+		WHERE ($1 = '' OR t.status = $1) AND ($2 = '' OR COALESCE(t.assigned_to, '') = $2)
+		ORDER BY t.id, p.npi
+	`, c.Query("status"), c.Query("assigned_to"))
+	if err != nil {
+		log.Println("查询任务列表错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务出错"})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		err := rows.Scan(&task.ID, &task.PhysicianID, &task.NPI, &task.Status, &task.AssignedTo, &task.Timestamp)
+		if err != nil {
+			log.Println("扫描任务数据错误:", err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// UpdateTaskStatus 管理员手动变更任务状态（取消、重新打开等），转换需合法
+func UpdateTaskStatus(c *gin.Context) {
+	npi, err := strconv.ParseInt(c.Param("npi"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的NPI号码"})
+		return
+	}
+
+	taskID, err := strconv.Atoi(c.Param("taskID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var request struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取医生ID
+	var physicianID int
+	err = db.DB.QueryRow("SELECT id FROM physicians WHERE npi = $1", npi).Scan(&physicianID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到该医生信息"})
+		return
+	}
+
+	// 开始事务
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Println("开始事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库事务错误"})
+		return
+	}
+
+	err = workflow.TransitionTask(tx, taskID, physicianID, request.Status, middleware.CurrentEvaluator(c), request.Reason)
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, workflow.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": "不允许的状态转换", "detail": err.Error()})
+		case errors.Is(err, workflow.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		default:
+			log.Println("更新任务状态错误:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务状态出错"})
+		}
+		return
+	}
+
+	// 提交事务
+	err = tx.Commit()
+	if err != nil {
+		log.Println("提交事务错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务出错"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "任务状态更新成功", "status": request.Status})
+}
+
+// GetTaskStatusHistory 获取任务的状态变更记录
+func GetTaskStatusHistory(c *gin.Context) {
+	npi, err := strconv.ParseInt(c.Param("npi"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的NPI号码"})
+		return
+	}
+
+	taskID, err := strconv.Atoi(c.Param("taskID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT h.id, h.task_id, h.physician_id, COALESCE(h.from_status, ''), h.to_status,
+		COALESCE(h.changed_by, ''), COALESCE(h.reason, ''), h.timestamp
+		FROM task_status_history h JOIN physicians p ON p.id = h.physician_id
+		WHERE h.task_id = $1 AND p.npi = $2
+		ORDER BY h.timestamp, h.id
+	`, taskID, npi)
+	if err != nil {
+		log.Println("查询任务状态记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务状态记录出错"})
+		return
+	}
+	defer rows.Close()
+
+	changes := []models.TaskStatusChange{}
+	for rows.Next() {
+		var change models.TaskStatusChange
+		err := rows.Scan(
+			&change.ID, &change.TaskID, &change.PhysicianID, &change.FromStatus,
+			&change.ToStatus, &change.ChangedBy, &change.Reason, &change.Timestamp,
+		)
+		if err != nil {
+			log.Println("扫描任务状态记录错误:", err)
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
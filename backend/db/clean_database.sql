@@ -2,11 +2,28 @@
 -- 删除数据的顺序很重要，要先删除有外键依赖的表
 
 -- 清空所有数据表
+TRUNCATE TABLE model_annotation_citations CASCADE;
+TRUNCATE TABLE human_annotation_citations CASCADE;
+TRUNCATE TABLE attention_check_alerts CASCADE;
+TRUNCATE TABLE attention_check_results CASCADE;
+TRUNCATE TABLE attention_checks CASCADE;
+TRUNCATE TABLE qualification_answers CASCADE;
+TRUNCATE TABLE qualification_attempts CASCADE;
+TRUNCATE TABLE gold_labels CASCADE;
+TRUNCATE TABLE annotator_reliability CASCADE;
+TRUNCATE TABLE consensus_labels CASCADE;
+TRUNCATE TABLE consensus_runs CASCADE;
+TRUNCATE TABLE adjudicated_labels CASCADE;
+TRUNCATE TABLE adjudication_tasks CASCADE;
+TRUNCATE TABLE machine_evaluation_revisions CASCADE;
+TRUNCATE TABLE human_annotation_revisions CASCADE;
 TRUNCATE TABLE machine_annotation_evaluation CASCADE;
 TRUNCATE TABLE trait_progress CASCADE;
 TRUNCATE TABLE human_annotations CASCADE;
 TRUNCATE TABLE model_annotations CASCADE;
+TRUNCATE TABLE models CASCADE;
 TRUNCATE TABLE reviews CASCADE;
+TRUNCATE TABLE task_status_history CASCADE;
 TRUNCATE TABLE tasks CASCADE;
 TRUNCATE TABLE physicians CASCADE;
 
@@ -16,4 +33,21 @@ ALTER SEQUENCE reviews_id_seq RESTART WITH 1;
 ALTER SEQUENCE model_annotations_id_seq RESTART WITH 1;
 ALTER SEQUENCE human_annotations_id_seq RESTART WITH 1;
 ALTER SEQUENCE trait_progress_id_seq RESTART WITH 1;
-ALTER SEQUENCE machine_annotation_evaluation_id_seq RESTART WITH 1; 
\ No newline at end of file
+ALTER SEQUENCE machine_annotation_evaluation_id_seq RESTART WITH 1;
+ALTER SEQUENCE task_status_history_id_seq RESTART WITH 1;
+ALTER SEQUENCE human_annotation_revisions_id_seq RESTART WITH 1;
+ALTER SEQUENCE machine_evaluation_revisions_id_seq RESTART WITH 1;
+ALTER SEQUENCE models_id_seq RESTART WITH 1;
+ALTER SEQUENCE adjudication_tasks_id_seq RESTART WITH 1;
+ALTER SEQUENCE adjudicated_labels_id_seq RESTART WITH 1;
+ALTER SEQUENCE consensus_runs_id_seq RESTART WITH 1;
+ALTER SEQUENCE consensus_labels_id_seq RESTART WITH 1;
+ALTER SEQUENCE annotator_reliability_id_seq RESTART WITH 1;
+ALTER SEQUENCE gold_labels_id_seq RESTART WITH 1;
+ALTER SEQUENCE qualification_attempts_id_seq RESTART WITH 1;
+ALTER SEQUENCE qualification_answers_id_seq RESTART WITH 1;
+ALTER SEQUENCE attention_checks_id_seq RESTART WITH 1;
+ALTER SEQUENCE attention_check_results_id_seq RESTART WITH 1;
+ALTER SEQUENCE attention_check_alerts_id_seq RESTART WITH 1;
+ALTER SEQUENCE human_annotation_citations_id_seq RESTART WITH 1;
+ALTER SEQUENCE model_annotation_citations_id_seq RESTART WITH 1;
\ No newline at end of file
@@ -1,24 +1,6 @@
 -- 修复trait_progress表数据一致性问题
 
--- 首先确保表存在
-CREATE TABLE IF NOT EXISTS trait_progress (
-    id SERIAL PRIMARY KEY,
-    physician_id INTEGER REFERENCES physicians(id),
-    task_id INTEGER,
-    evaluator TEXT,
-    trait TEXT,
-    human_annotation_completed BOOLEAN DEFAULT FALSE,
-    machine_evaluation_completed BOOLEAN DEFAULT FALSE,
-    review_completed BOOLEAN DEFAULT FALSE,
-    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(physician_id, task_id, evaluator, trait)
-);
-
--- 创建索引提高查询性能
-CREATE INDEX IF NOT EXISTS idx_trait_progress_physician_id ON trait_progress(physician_id);
-CREATE INDEX IF NOT EXISTS idx_trait_progress_task_id ON trait_progress(task_id);
-CREATE INDEX IF NOT EXISTS idx_trait_progress_evaluator ON trait_progress(evaluator);
-CREATE INDEX IF NOT EXISTS idx_trait_progress_trait ON trait_progress(trait);
+-- 表结构由 cmd/migrate 维护，本脚本只修复数据
 
 -- 清理不一致的数据并修复
 -- 查找有人类标注但没有进度记录的情况
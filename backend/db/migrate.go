@@ -0,0 +1,263 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey 执行迁移时持有的事务级advisory lock，防止多个进程同时迁移
+const migrationLockKey = 5813001
+
+// migrationFilePattern 迁移文件名：<版本号>_<名称>.up.sql / .down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrSchemaOutdated 数据库结构落后于程序内置的迁移
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// Migration 一个版本的迁移，Up升级，Down回退
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// AppliedMigration schema_migrations中记录的已执行迁移
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrations 按版本号升序返回程序内置的全部迁移
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must start at 1 without gaps, found %04d_%s", m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+// LatestVersion 程序内置的最新迁移版本
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// ensureMigrationTable 创建记录已执行迁移的schema_migrations表
+func ensureMigrationTable(conn *sql.DB) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// migrationTableExists schema_migrations表是否存在，只读检查时不创建
+func migrationTableExists(conn *sql.DB) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	return exists, err
+}
+
+// AppliedMigrations 返回数据库中已执行的迁移，按版本号升序
+func AppliedMigrations(conn *sql.DB) ([]AppliedMigration, error) {
+	exists, err := migrationTableExists(conn)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := conn.Query(`SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion 数据库当前的迁移版本，未执行过任何迁移时为0
+func SchemaVersion(conn *sql.DB) (int, error) {
+	exists, err := migrationTableExists(conn)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err = conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// MigrateTo 将数据库升级或回退到指定版本，每个版本在独立事务中执行。
+// onStep在每个版本执行完成后调用，可为nil
+func MigrateTo(conn *sql.DB, target int, onStep func(m Migration, up bool)) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if target < 0 || target > len(migrations) {
+		return fmt.Errorf("target version %d out of range 0..%d", target, len(migrations))
+	}
+	if err := ensureMigrationTable(conn); err != nil {
+		return err
+	}
+
+	for {
+		done, err := migrateStep(conn, migrations, target, onStep)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// MigrateUp 执行全部未执行的迁移
+func MigrateUp(conn *sql.DB, onStep func(m Migration, up bool)) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	return MigrateTo(conn, latest, onStep)
+}
+
+// migrateStep 在事务中向target前进或后退一个版本，已到达target时返回true。
+// 当前版本在持有advisory lock后读取，并发执行的迁移进程不会重复执行同一版本
+func migrateStep(conn *sql.DB, migrations []Migration, target int, onStep func(m Migration, up bool)) (bool, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockKey); err != nil {
+		return false, err
+	}
+
+	var current int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return false, err
+	}
+	if current > len(migrations) {
+		return false, fmt.Errorf("database is at version %d, newer than the latest known migration %d", current, len(migrations))
+	}
+	if current == target {
+		return true, nil
+	}
+
+	up := current < target
+	if up {
+		m := migrations[current]
+		if _, err := tx.Exec(m.Up); err != nil {
+			return false, fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, err
+		}
+		if onStep != nil {
+			onStep(m, true)
+		}
+		return false, nil
+	}
+
+	m := migrations[current-1]
+	if _, err := tx.Exec(m.Down); err != nil {
+		return false, fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if onStep != nil {
+		onStep(m, false)
+	}
+	return false, nil
+}
+
+// CheckSchema 检查数据库是否已执行全部内置迁移，服务启动时调用。
+// 落后时返回ErrSchemaOutdated
+func CheckSchema(conn *sql.DB) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion(conn)
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrSchemaOutdated, current, latest)
+	}
+	if current > latest {
+		return fmt.Errorf("database is at version %d, newer than the latest known migration %d", current, latest)
+	}
+	return nil
+}
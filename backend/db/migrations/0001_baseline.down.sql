@@ -0,0 +1,8 @@
+-- 删除基础表结构及其中的全部数据
+DROP TABLE IF EXISTS machine_annotation_evaluation CASCADE;
+DROP TABLE IF EXISTS trait_progress CASCADE;
+DROP TABLE IF EXISTS human_annotations CASCADE;
+DROP TABLE IF EXISTS tasks CASCADE;
+DROP TABLE IF EXISTS model_annotations CASCADE;
+DROP TABLE IF EXISTS reviews CASCADE;
+DROP TABLE IF EXISTS physicians CASCADE;
@@ -0,0 +1,131 @@
+-- 基础表结构
+-- 合并原 init.sql、add_tables.sql、fix_progress.sql 和 migration_new_workflow.sql。
+-- 全部语句可重复执行，已有数据库执行时只补齐缺少的部分。
+
+-- 旧工作流中不再使用的表
+DROP TABLE IF EXISTS model_rankings;
+DROP TABLE IF EXISTS model_evaluations;
+
+-- 创建physicians表
+CREATE TABLE IF NOT EXISTS physicians (
+    id SERIAL PRIMARY KEY,
+    phy_id BIGINT,
+    npi BIGINT UNIQUE,
+    first_name TEXT,
+    last_name TEXT,
+    gender TEXT,
+    credential TEXT,
+    specialty TEXT,
+    practice_zip5 TEXT,
+    business_zip5 TEXT,
+    biography_doc TEXT,
+    education_doc TEXT,
+    num_reviews INTEGER,
+    doc_name TEXT,
+    zip3 TEXT,
+    zip2 TEXT,
+    zipcode TEXT,
+    state TEXT,
+    region TEXT
+);
+
+-- 创建reviews表
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    review_index INTEGER,
+    source TEXT,
+    date TIMESTAMP,
+    text TEXT
+);
+
+-- 创建model_annotations表
+CREATE TABLE IF NOT EXISTS model_annotations (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    model_name TEXT,
+    trait TEXT,
+    score TEXT,
+    consistency TEXT,
+    sufficiency TEXT,
+    evidence TEXT
+);
+
+-- 创建tasks表：任务号在每位医生下编号，主键为(id, physician_id)
+CREATE TABLE IF NOT EXISTS tasks (
+    id INTEGER,
+    physician_id INTEGER REFERENCES physicians(id),
+    status TEXT DEFAULT 'pending',
+    assigned_to TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, physician_id)
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- init.sql 创建的tasks表主键只有id（SERIAL），改为(id, physician_id)
+DO $$
+BEGIN
+    IF (SELECT COUNT(*) FROM pg_index i
+        JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = 'tasks'::regclass AND i.indisprimary) = 1 THEN
+        ALTER TABLE tasks DROP CONSTRAINT tasks_pkey;
+        ALTER TABLE tasks ALTER COLUMN id DROP DEFAULT;
+        ALTER TABLE tasks ADD PRIMARY KEY (id, physician_id);
+    END IF;
+END $$;
+
+-- 创建human_annotations表
+CREATE TABLE IF NOT EXISTS human_annotations (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    evaluator TEXT,
+    task_id INTEGER,
+    trait TEXT,
+    score INTEGER,
+    consistency INTEGER,
+    sufficiency INTEGER,
+    evidence TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (physician_id, evaluator, task_id, trait)
+);
+
+-- 创建trait_progress表：追踪用户在每个trait上的进度
+CREATE TABLE IF NOT EXISTS trait_progress (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    task_id INTEGER,
+    evaluator TEXT,
+    trait TEXT, -- openness, conscientiousness, extraversion, agreeableness, neuroticism
+    human_annotation_completed BOOLEAN DEFAULT FALSE,
+    machine_evaluation_completed BOOLEAN DEFAULT FALSE,
+    review_completed BOOLEAN DEFAULT FALSE,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (physician_id, task_id, evaluator, trait)
+);
+
+-- 创建machine_annotation_evaluation表：存储对机器标注的简单评价
+CREATE TABLE IF NOT EXISTS machine_annotation_evaluation (
+    id SERIAL PRIMARY KEY,
+    model_annotation_id INTEGER REFERENCES model_annotations(id),
+    physician_id INTEGER REFERENCES physicians(id),
+    task_id INTEGER,
+    evaluator TEXT,
+    trait TEXT,
+    model_name TEXT,
+    rating TEXT CHECK (rating IN ('thumb_up', 'thumb_down', 'just_soso')),
+    comment TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (model_annotation_id, evaluator, task_id)
+);
+
+-- 创建索引以提高查询性能
+CREATE INDEX IF NOT EXISTS idx_physicians_npi ON physicians(npi);
+CREATE INDEX IF NOT EXISTS idx_reviews_physician_id ON reviews(physician_id);
+CREATE INDEX IF NOT EXISTS idx_model_annotations_physician_id ON model_annotations(physician_id);
+CREATE INDEX IF NOT EXISTS idx_human_annotations_physician_id ON human_annotations(physician_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_physician_id ON tasks(physician_id);
+CREATE INDEX IF NOT EXISTS idx_trait_progress_physician_task ON trait_progress(physician_id, task_id);
+CREATE INDEX IF NOT EXISTS idx_trait_progress_evaluator_trait ON trait_progress(evaluator, trait);
+CREATE INDEX IF NOT EXISTS idx_machine_evaluation_physician_task ON machine_annotation_evaluation(physician_id, task_id);
+CREATE INDEX IF NOT EXISTS idx_machine_evaluation_evaluator_trait ON machine_annotation_evaluation(evaluator, trait);
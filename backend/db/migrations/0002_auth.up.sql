@@ -0,0 +1,9 @@
+-- 用户认证迁移
+-- 创建users表：标注用户账号，密码使用bcrypt哈希存储
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username TEXT UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login TIMESTAMP
+);
@@ -0,0 +1,6 @@
+-- 用户角色迁移
+-- 为users表添加角色字段：annotator（标注员）、adjudicator（仲裁员）、admin（项目管理员）
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'annotator';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('annotator', 'adjudicator', 'admin'));
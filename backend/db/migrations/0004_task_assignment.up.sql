@@ -0,0 +1,3 @@
+-- 任务指派迁移
+-- 任务不再在打开时自动创建，由管理员批量创建并指派
+CREATE INDEX IF NOT EXISTS idx_tasks_assigned_to ON tasks(assigned_to);
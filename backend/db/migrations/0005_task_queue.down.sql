@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_status;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS pool_claim;
+ALTER TABLE tasks DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS lease_owner;
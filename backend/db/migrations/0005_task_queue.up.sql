@@ -0,0 +1,8 @@
+-- 任务队列迁移
+-- lease_owner/lease_expires_at：任务租约，防止同一任务被多人同时领取
+-- pool_claim：任务是否由用户从未指派的任务池中领取
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS lease_owner TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS pool_claim BOOLEAN DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
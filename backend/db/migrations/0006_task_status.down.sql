@@ -0,0 +1,4 @@
+-- 状态历史一并删除；任务状态保留当前值
+DROP TABLE IF EXISTS task_status_history CASCADE;
+
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_status_check;
@@ -0,0 +1,37 @@
+-- 任务状态机迁移
+-- 任务状态：pending -> in_progress -> completed，另有 cancelled、reopened
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_status_check;
+ALTER TABLE tasks ADD CONSTRAINT tasks_status_check
+    CHECK (status IN ('pending', 'in_progress', 'completed', 'cancelled', 'reopened'));
+
+-- 创建task_status_history表：记录每次任务状态变更
+CREATE TABLE IF NOT EXISTS task_status_history (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    physician_id INTEGER REFERENCES physicians(id),
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    changed_by TEXT,
+    reason TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_status_history_task ON task_status_history(task_id, physician_id);
+
+-- 回填：指派人已完成全部trait回顾的任务标记为completed
+WITH finished AS (
+    SELECT t.id, t.physician_id, t.status
+    FROM tasks t
+    JOIN trait_progress p
+      ON p.task_id = t.id AND p.physician_id = t.physician_id AND p.evaluator = t.assigned_to
+    WHERE t.status IN ('pending', 'in_progress') AND p.review_completed = TRUE
+    GROUP BY t.id, t.physician_id, t.status
+    HAVING COUNT(DISTINCT p.trait) = 5
+), history AS (
+    INSERT INTO task_status_history (task_id, physician_id, from_status, to_status, changed_by, reason)
+    SELECT id, physician_id, status, 'completed', 'migration', 'all traits reviewed'
+    FROM finished
+)
+UPDATE tasks t SET status = 'completed', timestamp = CURRENT_TIMESTAMP
+FROM finished f
+WHERE t.id = f.id AND t.physician_id = f.physician_id;
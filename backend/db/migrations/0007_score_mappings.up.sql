@@ -0,0 +1,27 @@
+-- 模型标签映射迁移
+-- 创建score_label_mappings表：将模型输出的文本标签映射到人类1-5评分尺度
+-- value为NULL表示该标签不参与人类与模型的比较（如No Evidence、N/A）
+CREATE TABLE IF NOT EXISTS score_label_mappings (
+    dimension TEXT NOT NULL CHECK (dimension IN ('score', 'consistency', 'sufficiency')),
+    label TEXT NOT NULL,
+    value NUMERIC,
+    PRIMARY KEY (dimension, label)
+);
+
+-- 默认映射：人类尺度 1=Very Low, 2=Low, 3=Moderate, 4=High, 5=Very High
+INSERT INTO score_label_mappings (dimension, label, value)
+SELECT d.dimension, l.label, l.value
+FROM (VALUES ('score'), ('consistency'), ('sufficiency')) AS d(dimension)
+CROSS JOIN (
+    VALUES
+        ('Very Low', 1.0),
+        ('Low', 2.0),
+        ('Low to Moderate', 2.5),
+        ('Moderate', 3.0),
+        ('Moderate to High', 3.5),
+        ('High', 4.0),
+        ('Very High', 5.0),
+        ('No Evidence', NULL),
+        ('N/A', NULL)
+) AS l(label, value)
+ON CONFLICT (dimension, label) DO NOTHING;
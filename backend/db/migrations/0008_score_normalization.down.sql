@@ -0,0 +1,9 @@
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS score_low;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS score_high;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS score_no_evidence;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS consistency_low;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS consistency_high;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS consistency_no_evidence;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS sufficiency_low;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS sufficiency_high;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS sufficiency_no_evidence;
@@ -0,0 +1,13 @@
+-- 模型评分规范化迁移
+-- 在保留原始文本的同时存储解析后的有序值（人类1-5评分尺度）：
+-- *_low/*_high 为范围上下界（单一等级时相同），*_no_evidence 表示"No Evidence"等无证据标签；
+-- 三列均为NULL表示原始文本无法解析。已有数据使用 cmd/normalize 回填。
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS score_low NUMERIC;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS score_high NUMERIC;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS score_no_evidence BOOLEAN;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS consistency_low NUMERIC;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS consistency_high NUMERIC;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS consistency_no_evidence BOOLEAN;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS sufficiency_low NUMERIC;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS sufficiency_high NUMERIC;
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS sufficiency_no_evidence BOOLEAN;
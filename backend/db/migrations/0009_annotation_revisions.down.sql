@@ -0,0 +1,3 @@
+-- 修订历史一并删除；human_annotations和machine_annotation_evaluation中的当前值保留
+DROP TABLE IF EXISTS machine_evaluation_revisions CASCADE;
+DROP TABLE IF EXISTS human_annotation_revisions CASCADE;
@@ -0,0 +1,59 @@
+-- 标注修订记录迁移
+-- human_annotations和machine_annotation_evaluation只保存当前值，每次提交另存一条不可变修订记录
+
+-- 创建human_annotation_revisions表：人类标注的每次提交
+CREATE TABLE IF NOT EXISTS human_annotation_revisions (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    task_id INTEGER,
+    evaluator TEXT,
+    trait TEXT,
+    revision INTEGER NOT NULL,
+    stage TEXT, -- 提交时的工作流阶段：human_annotation 或 review_and_modify
+    score INTEGER,
+    consistency INTEGER,
+    sufficiency INTEGER,
+    evidence TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (physician_id, task_id, evaluator, trait, revision)
+);
+
+-- 创建machine_evaluation_revisions表：机器标注评价的每次提交
+CREATE TABLE IF NOT EXISTS machine_evaluation_revisions (
+    id SERIAL PRIMARY KEY,
+    model_annotation_id INTEGER REFERENCES model_annotations(id),
+    physician_id INTEGER REFERENCES physicians(id),
+    task_id INTEGER,
+    evaluator TEXT,
+    trait TEXT,
+    model_name TEXT,
+    revision INTEGER NOT NULL,
+    stage TEXT,
+    rating TEXT CHECK (rating IN ('thumb_up', 'thumb_down', 'just_soso')),
+    comment TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (model_annotation_id, task_id, evaluator, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_human_revisions_physician_task ON human_annotation_revisions(physician_id, task_id);
+CREATE INDEX IF NOT EXISTS idx_machine_revisions_physician_task ON machine_evaluation_revisions(physician_id, task_id);
+
+-- 回填：已有的当前值作为第1版，提交阶段未知因此stage留空
+INSERT INTO human_annotation_revisions
+    (physician_id, task_id, evaluator, trait, revision, stage, score, consistency, sufficiency, evidence, timestamp)
+SELECT physician_id, task_id, evaluator, trait, 1, NULL, score, consistency, sufficiency, evidence, timestamp
+FROM human_annotations h
+WHERE NOT EXISTS (
+    SELECT 1 FROM human_annotation_revisions r
+    WHERE r.physician_id = h.physician_id AND r.task_id = h.task_id
+      AND r.evaluator = h.evaluator AND r.trait = h.trait
+);
+
+INSERT INTO machine_evaluation_revisions
+    (model_annotation_id, physician_id, task_id, evaluator, trait, model_name, revision, stage, rating, comment, timestamp)
+SELECT model_annotation_id, physician_id, task_id, evaluator, trait, model_name, 1, NULL, rating, comment, timestamp
+FROM machine_annotation_evaluation e
+WHERE NOT EXISTS (
+    SELECT 1 FROM machine_evaluation_revisions r
+    WHERE r.model_annotation_id = e.model_annotation_id AND r.task_id = e.task_id AND r.evaluator = e.evaluator
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reviews_source;
+DROP INDEX IF EXISTS idx_reviews_physician_date;
@@ -0,0 +1,4 @@
+-- 评论日期与来源索引迁移
+-- 导入时从 <meta>#序号 - 时间 - 来源</meta> 中解析出真实的date和source，用于排序和筛选
+CREATE INDEX IF NOT EXISTS idx_reviews_physician_date ON reviews(physician_id, date);
+CREATE INDEX IF NOT EXISTS idx_reviews_source ON reviews(source);
@@ -0,0 +1,4 @@
+-- model_annotations.model_name保留了显示名称，删除model_id后仍可按名称区分模型
+DROP INDEX IF EXISTS idx_model_annotations_model_id;
+ALTER TABLE model_annotations DROP COLUMN IF EXISTS model_id;
+DROP TABLE IF EXISTS models CASCADE;
@@ -0,0 +1,39 @@
+-- 模型注册表迁移
+-- 导入时根据 output_<provider>_<model> 字段自动注册模型，model_annotations通过model_id关联
+CREATE TABLE IF NOT EXISTS models (
+    id SERIAL PRIMARY KEY,
+    provider TEXT NOT NULL,
+    version TEXT NOT NULL,
+    display_name TEXT NOT NULL,
+    run_date DATE,
+    prompt_version TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, version, prompt_version)
+);
+
+ALTER TABLE model_annotations ADD COLUMN IF NOT EXISTS model_id INTEGER REFERENCES models(id);
+CREATE INDEX IF NOT EXISTS idx_model_annotations_model_id ON model_annotations(model_id);
+
+-- 原导入代码中写死的六个模型
+INSERT INTO models (provider, version, display_name)
+VALUES
+    ('openai', 'gpt-4.1', 'GPT-4'),
+    ('openai', 'gpt-4o', 'GPT-4o'),
+    ('gemini', 'gemini-2.5-flash-preview-05-20', 'Gemini-2.5-Flash-Preview'),
+    ('gemini', 'gemini-2.0-flash', 'Gemini-2.0-Flash'),
+    ('gemini', 'gemini-2.5-pro-preview-05-06', 'Gemini-2.5-Pro-Preview'),
+    ('anthropic', 'claude-3-7-sonnet-20250219', 'Claude-3.7-Sonnet')
+ON CONFLICT (provider, version, prompt_version) DO NOTHING;
+
+-- 其余已有model_name登记为provider未知的模型
+INSERT INTO models (provider, version, display_name)
+SELECT DISTINCT 'unknown', a.model_name, a.model_name
+FROM model_annotations a
+WHERE a.model_name IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM models m WHERE m.display_name = a.model_name)
+ON CONFLICT (provider, version, prompt_version) DO NOTHING;
+
+-- 回填model_id
+UPDATE model_annotations a SET model_id = m.id
+FROM models m
+WHERE a.model_id IS NULL AND m.display_name = a.model_name;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_model_annotations_physician_model_trait;
+DROP INDEX IF EXISTS idx_reviews_physician_index;
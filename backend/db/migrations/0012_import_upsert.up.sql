@@ -0,0 +1,7 @@
+-- 可重复导入迁移
+-- 导入程序按以下唯一键插入或更新评论和模型标注，重复运行不会产生重复数据。
+-- 若已有重复数据，创建索引会失败，可先用以下查询定位：
+--   SELECT physician_id, review_index, COUNT(*) FROM reviews GROUP BY 1, 2 HAVING COUNT(*) > 1;
+--   SELECT physician_id, model_id, trait, COUNT(*) FROM model_annotations GROUP BY 1, 2, 3 HAVING COUNT(*) > 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_physician_index ON reviews(physician_id, review_index);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_annotations_physician_model_trait ON model_annotations(physician_id, model_id, trait);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS adjudicated_labels CASCADE;
+DROP TABLE IF EXISTS adjudication_tasks CASCADE;
@@ -0,0 +1,36 @@
+-- 仲裁迁移
+-- 多位评分者对同一医生同一trait的人类标注分歧超过阈值时创建仲裁任务，由adjudicator给出最终标签
+
+-- 创建adjudication_tasks表：每条记录是一次需要仲裁的分歧
+CREATE TABLE IF NOT EXISTS adjudication_tasks (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    trait TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'cancelled')),
+    assigned_to TEXT,
+    spread NUMERIC NOT NULL,    -- 创建时各评分者评分的最大差值
+    threshold NUMERIC NOT NULL, -- 创建时使用的阈值
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+
+-- 同一医生同一trait同时只有一个未完成的仲裁
+CREATE UNIQUE INDEX IF NOT EXISTS idx_adjudication_tasks_open
+    ON adjudication_tasks(physician_id, trait) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_adjudication_tasks_assigned_to ON adjudication_tasks(assigned_to, status);
+
+-- 创建adjudicated_labels表：仲裁给出的最终标签及理由
+CREATE TABLE IF NOT EXISTS adjudicated_labels (
+    id SERIAL PRIMARY KEY,
+    adjudication_id INTEGER UNIQUE REFERENCES adjudication_tasks(id),
+    physician_id INTEGER REFERENCES physicians(id),
+    trait TEXT NOT NULL,
+    score INTEGER NOT NULL CHECK (score BETWEEN 1 AND 5),
+    consistency INTEGER NOT NULL CHECK (consistency BETWEEN 1 AND 5),
+    sufficiency INTEGER NOT NULL CHECK (sufficiency BETWEEN 1 AND 5),
+    rationale TEXT NOT NULL,
+    adjudicator TEXT NOT NULL,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_adjudicated_labels_physician_trait ON adjudicated_labels(physician_id, trait);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS annotator_reliability CASCADE;
+DROP TABLE IF EXISTS consensus_labels CASCADE;
+DROP TABLE IF EXISTS consensus_runs CASCADE;
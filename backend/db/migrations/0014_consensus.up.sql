@@ -0,0 +1,42 @@
+-- 共识标签迁移
+-- 每次计算共识是一个版本（consensus_runs），保存该版本的共识标签和评分者可靠性估计
+
+-- 创建consensus_runs表：一次共识计算及其方法和参数
+CREATE TABLE IF NOT EXISTS consensus_runs (
+    id SERIAL PRIMARY KEY,
+    method TEXT NOT NULL CHECK (method IN ('majority', 'median', 'dawid_skene')),
+    parameters JSONB NOT NULL DEFAULT '{}', -- 筛选条件和EM迭代参数
+    units INTEGER NOT NULL DEFAULT 0,        -- 共识标签条数
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_consensus_runs_method ON consensus_runs(method, id);
+
+-- 创建consensus_labels表：某版本中每个医生、trait、维度的共识标签
+CREATE TABLE IF NOT EXISTS consensus_labels (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES consensus_runs(id) ON DELETE CASCADE,
+    physician_id INTEGER REFERENCES physicians(id),
+    trait TEXT NOT NULL,
+    dimension TEXT NOT NULL CHECK (dimension IN ('score', 'consistency', 'sufficiency')),
+    label NUMERIC NOT NULL,      -- 共识标签，中位数方法可能为x.5
+    confidence NUMERIC NOT NULL, -- 0-1，含义随方法不同
+    annotators INTEGER NOT NULL, -- 参与的评分者人数
+    UNIQUE (run_id, physician_id, trait, dimension)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consensus_labels_physician_trait ON consensus_labels(physician_id, trait);
+
+-- 创建annotator_reliability表：某版本中每位评分者在每个trait、维度上的可靠性
+CREATE TABLE IF NOT EXISTS annotator_reliability (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES consensus_runs(id) ON DELETE CASCADE,
+    evaluator TEXT NOT NULL,
+    trait TEXT NOT NULL,
+    dimension TEXT NOT NULL,
+    items INTEGER NOT NULL,    -- 评分者参与的条目数
+    accuracy NUMERIC,          -- 与真实标签一致的概率估计，条目不足时为空
+    confusion JSONB,           -- Dawid-Skene混淆矩阵：真实标签 -> 评分 -> 概率
+    UNIQUE (run_id, evaluator, trait, dimension)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS qualification_answers CASCADE;
+DROP TABLE IF EXISTS qualification_attempts CASCADE;
+DROP TABLE IF EXISTS gold_labels CASCADE;
+ALTER TABLE users DROP COLUMN IF EXISTS qualified_at;
@@ -0,0 +1,51 @@
+-- 资格测试迁移
+-- 管理员为部分医生/trait设定参考标签（gold_labels），新用户先完成资格测试，通过后才能领取和被指派正式任务
+
+-- 用户通过资格测试的时间，为空表示尚未通过
+ALTER TABLE users ADD COLUMN IF NOT EXISTS qualified_at TIMESTAMP;
+
+-- 已有用户视为已通过
+UPDATE users SET qualified_at = COALESCE(created_at, CURRENT_TIMESTAMP) WHERE qualified_at IS NULL;
+
+-- 创建gold_labels表：医生/trait的参考标签
+CREATE TABLE IF NOT EXISTS gold_labels (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    trait TEXT NOT NULL,
+    score INTEGER NOT NULL CHECK (score BETWEEN 1 AND 5),
+    consistency INTEGER NOT NULL CHECK (consistency BETWEEN 1 AND 5),
+    sufficiency INTEGER NOT NULL CHECK (sufficiency BETWEEN 1 AND 5),
+    qualification BOOLEAN NOT NULL DEFAULT TRUE, -- 是否用于资格测试
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (physician_id, trait)
+);
+
+-- 创建qualification_attempts表：一次资格测试，完成后记录得分和是否通过
+CREATE TABLE IF NOT EXISTS qualification_attempts (
+    id SERIAL PRIMARY KEY,
+    evaluator TEXT NOT NULL,
+    items INTEGER NOT NULL,
+    within_one NUMERIC, -- 与参考标签相差不超过1分的比例
+    kappa NUMERIC,      -- 与参考标签的Cohen's kappa
+    passed BOOLEAN,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+-- 每位用户同时只有一次未完成的测试
+CREATE UNIQUE INDEX IF NOT EXISTS idx_qualification_attempts_open
+    ON qualification_attempts(evaluator) WHERE completed_at IS NULL;
+
+-- 创建qualification_answers表：测试开始时确定题目和顺序，作答后填入评分
+CREATE TABLE IF NOT EXISTS qualification_answers (
+    id SERIAL PRIMARY KEY,
+    attempt_id INTEGER NOT NULL REFERENCES qualification_attempts(id) ON DELETE CASCADE,
+    gold_label_id INTEGER NOT NULL REFERENCES gold_labels(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    score INTEGER CHECK (score BETWEEN 1 AND 5),
+    consistency INTEGER CHECK (consistency BETWEEN 1 AND 5),
+    sufficiency INTEGER CHECK (sufficiency BETWEEN 1 AND 5),
+    answered_at TIMESTAMP,
+    UNIQUE (attempt_id, gold_label_id)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS attention_check_alerts CASCADE;
+DROP TABLE IF EXISTS attention_check_results CASCADE;
+DROP TABLE IF EXISTS attention_checks CASCADE;
+ALTER TABLE gold_labels DROP COLUMN IF EXISTS attention_check;
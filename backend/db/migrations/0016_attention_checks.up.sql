@@ -0,0 +1,48 @@
+-- 注意力检查迁移
+-- 领取任务时按比例为评分者插入带参考标签的医生任务，与普通任务无法区分；
+-- 标注阶段的首次提交与参考标签比较，准确率过低时生成告警
+
+-- 参考标签是否用于注意力检查
+ALTER TABLE gold_labels ADD COLUMN IF NOT EXISTS attention_check BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 创建attention_checks表：插入到评分者队列中的检查任务
+CREATE TABLE IF NOT EXISTS attention_checks (
+    id SERIAL PRIMARY KEY,
+    evaluator TEXT NOT NULL,
+    task_id INTEGER NOT NULL,
+    physician_id INTEGER REFERENCES physicians(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (task_id, physician_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attention_checks_evaluator ON attention_checks(evaluator);
+
+-- 创建attention_check_results表：每个trait在标注阶段的首次提交和当时的参考标签
+CREATE TABLE IF NOT EXISTS attention_check_results (
+    id SERIAL PRIMARY KEY,
+    check_id INTEGER NOT NULL REFERENCES attention_checks(id) ON DELETE CASCADE,
+    trait TEXT NOT NULL,
+    score INTEGER NOT NULL,
+    consistency INTEGER NOT NULL,
+    sufficiency INTEGER NOT NULL,
+    expected_score INTEGER NOT NULL,
+    expected_consistency INTEGER NOT NULL,
+    expected_sufficiency INTEGER NOT NULL,
+    answered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (check_id, trait)
+);
+
+-- 创建attention_check_alerts表：评分者近期检查准确率低于阈值时的告警
+CREATE TABLE IF NOT EXISTS attention_check_alerts (
+    id SERIAL PRIMARY KEY,
+    evaluator TEXT NOT NULL,
+    accuracy NUMERIC NOT NULL, -- 告警时近期检查中与参考标签相差不超过1分的比例
+    results INTEGER NOT NULL,  -- 计算准确率用到的检查结果数
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_by TEXT,
+    acknowledged_at TIMESTAMP
+);
+
+-- 每位评分者同时只有一条未处理的告警
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attention_check_alerts_open
+    ON attention_check_alerts(evaluator) WHERE acknowledged_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS model_annotation_citations CASCADE;
+DROP TABLE IF EXISTS human_annotation_citations CASCADE;
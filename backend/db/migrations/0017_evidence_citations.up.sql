@@ -0,0 +1,33 @@
+-- 证据引用迁移
+-- 标注的evidence是自由文本，无法知道依据了哪条评论；引用记录评论ID、字符偏移量和引用原文，提交时按reviews.text校验
+
+-- 创建human_annotation_citations表：人类标注每次提交（修订）引用的评论片段，与human_annotation_revisions对应
+CREATE TABLE IF NOT EXISTS human_annotation_citations (
+    id SERIAL PRIMARY KEY,
+    physician_id INTEGER REFERENCES physicians(id),
+    task_id INTEGER,
+    evaluator TEXT,
+    trait TEXT,
+    revision INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    review_id INTEGER NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL CHECK (start_offset >= 0), -- 按字符（Unicode码点）计算，包含
+    end_offset INTEGER NOT NULL CHECK (end_offset > start_offset), -- 不包含
+    quote TEXT NOT NULL,
+    UNIQUE (physician_id, task_id, evaluator, trait, revision, position)
+);
+
+-- 创建model_annotation_citations表：模型标注引用的评论片段，随模型输出导入
+CREATE TABLE IF NOT EXISTS model_annotation_citations (
+    id SERIAL PRIMARY KEY,
+    model_annotation_id INTEGER NOT NULL REFERENCES model_annotations(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    review_id INTEGER NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL CHECK (start_offset >= 0),
+    end_offset INTEGER NOT NULL CHECK (end_offset > start_offset),
+    quote TEXT NOT NULL,
+    UNIQUE (model_annotation_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_human_citations_review ON human_annotation_citations(review_id);
+CREATE INDEX IF NOT EXISTS idx_model_citations_review ON model_annotation_citations(review_id);
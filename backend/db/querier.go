@@ -0,0 +1,15 @@
+package db
+
+import "database/sql"
+
+// Querier 可执行查询的数据库句柄（*sql.DB 或 *sql.Tx）
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Scanner 可读取一行结果（*sql.Row 或 *sql.Rows）
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
@@ -0,0 +1,20 @@
+package db
+
+import _ "embed"
+
+// 维护脚本编译进程序，不依赖运行时的工作目录
+
+// CleanSQL 清空所有数据、保留表结构
+//
+//go:embed clean_database.sql
+var CleanSQL string
+
+// RebuildSQL 删除所有表，之后需执行全部迁移
+//
+//go:embed rebuild_database.sql
+var RebuildSQL string
+
+// TestDataSQL 重建数据库后插入的测试数据
+//
+//go:embed test_data.sql
+var TestDataSQL string
@@ -0,0 +1,224 @@
+package evidence
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// ValidationError 某条引用与评论原文不符
+type ValidationError struct {
+	Index  int // 引用在列表中的位置，从0开始
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("citation %d: %s", e.Index, e.Reason)
+}
+
+// review 校验引用时用到的评论
+type review struct {
+	ID    int
+	Index int
+	Text  string
+}
+
+// Validate 按reviews.text校验引用，并补全ReviewID、ReviewIndex和Quote（见checkCitation）。
+// 评论必须属于该医生
+func Validate(q db.Querier, physicianID int, citations []models.EvidenceCitation) error {
+	if len(citations) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(`SELECT id, review_index, COALESCE(text, '') FROM reviews WHERE physician_id = $1`, physicianID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := map[int]review{}
+	byIndex := map[int]review{}
+	for rows.Next() {
+		var r review
+		var index sql.NullInt64
+		if err := rows.Scan(&r.ID, &index, &r.Text); err != nil {
+			return err
+		}
+		r.Index = int(index.Int64)
+		byID[r.ID] = r
+		if index.Valid {
+			byIndex[r.Index] = r
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range citations {
+		if err := checkCitation(i, &citations[i], byID, byIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCitation 校验第i条引用并补全。评论用review_id指定，review_id为0时按review_index查找；
+// 区间按字符（Unicode码点）计算，与citationColumns中PostgreSQL的substr一致，必须落在评论正文内；
+// quote为空时填入原文，不为空时必须与原文一致
+func checkCitation(i int, citation *models.EvidenceCitation, byID, byIndex map[int]review) error {
+	r, ok := byID[citation.ReviewID]
+	if citation.ReviewID == 0 {
+		r, ok = byIndex[citation.ReviewIndex]
+	}
+	if !ok {
+		return &ValidationError{i, "review does not belong to this physician"}
+	}
+
+	text := []rune(r.Text)
+	if citation.Start < 0 || citation.End <= citation.Start || citation.End > len(text) {
+		return &ValidationError{i, fmt.Sprintf("span [%d, %d) is outside the review text (%d characters)",
+			citation.Start, citation.End, len(text))}
+	}
+	quote := string(text[citation.Start:citation.End])
+	if citation.Quote != "" && citation.Quote != quote {
+		return &ValidationError{i, fmt.Sprintf("quote does not match the review text %q", quote)}
+	}
+
+	citation.ReviewID, citation.ReviewIndex, citation.Quote = r.ID, r.Index, quote
+	citation.Stale = false
+	return nil
+}
+
+// citationColumns 查询引用时的列，stale表示评论原文已与保存的quote不一致
+const citationColumns = `c.review_id, COALESCE(r.review_index, 0), c.start_offset, c.end_offset, c.quote,
+	substr(r.text, c.start_offset + 1, c.end_offset - c.start_offset) IS DISTINCT FROM c.quote`
+
+func scanCitation(rows *sql.Rows, dest ...interface{}) (models.EvidenceCitation, error) {
+	var citation models.EvidenceCitation
+	err := rows.Scan(append(dest, &citation.ReviewID, &citation.ReviewIndex,
+		&citation.Start, &citation.End, &citation.Quote, &citation.Stale)...)
+	return citation, err
+}
+
+// SaveHuman 保存人类标注最新一次修订的引用，须在recordHumanRevision之后调用
+func SaveHuman(q db.Querier, annotation models.HumanAnnotation) error {
+	for i, citation := range annotation.Citations {
+		_, err := q.Exec(`
+			INSERT INTO human_annotation_citations
+			(physician_id, task_id, evaluator, trait, revision, position, review_id, start_offset, end_offset, quote)
+			SELECT $1, $2, $3, $4, MAX(revision), $5, $6, $7, $8, $9
+			FROM human_annotation_revisions
+			WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+		`, annotation.PhysicianID, annotation.TaskID, annotation.Evaluator, annotation.Trait,
+			i+1, citation.ReviewID, citation.Start, citation.End, citation.Quote)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadHuman 读取某位评分者在某任务某trait上各次修订的引用，按修订号分组
+func LoadHuman(q db.Querier, physicianID, taskID int, evaluator, trait string) (map[int][]models.EvidenceCitation, error) {
+	rows, err := q.Query(`
+		SELECT c.revision, `+citationColumns+`
+		FROM human_annotation_citations c JOIN reviews r ON r.id = c.review_id
+		WHERE c.physician_id = $1 AND c.task_id = $2 AND c.evaluator = $3 AND c.trait = $4
+		ORDER BY c.revision, c.position
+	`, physicianID, taskID, evaluator, trait)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	citations := map[int][]models.EvidenceCitation{}
+	for rows.Next() {
+		var revision int
+		citation, err := scanCitation(rows, &revision)
+		if err != nil {
+			return nil, err
+		}
+		citations[revision] = append(citations[revision], citation)
+	}
+	return citations, rows.Err()
+}
+
+// LoadCurrentHuman 读取某位评分者在某任务上各trait人类标注当前值（最新修订）的引用，按trait分组
+func LoadCurrentHuman(q db.Querier, physicianID, taskID int, evaluator string) (map[string][]models.EvidenceCitation, error) {
+	rows, err := q.Query(`
+		SELECT c.trait, `+citationColumns+`
+		FROM human_annotation_citations c JOIN reviews r ON r.id = c.review_id
+		WHERE c.physician_id = $1 AND c.task_id = $2 AND c.evaluator = $3
+		AND c.revision = (
+			SELECT MAX(v.revision) FROM human_annotation_revisions v
+			WHERE v.physician_id = c.physician_id AND v.task_id = c.task_id
+			AND v.evaluator = c.evaluator AND v.trait = c.trait
+		)
+		ORDER BY c.trait, c.position
+	`, physicianID, taskID, evaluator)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	citations := map[string][]models.EvidenceCitation{}
+	for rows.Next() {
+		var trait string
+		citation, err := scanCitation(rows, &trait)
+		if err != nil {
+			return nil, err
+		}
+		citations[trait] = append(citations[trait], citation)
+	}
+	return citations, rows.Err()
+}
+
+// ReplaceModel 用新引用替换模型标注的全部引用
+func ReplaceModel(q db.Querier, modelAnnotationID int, citations []models.EvidenceCitation) error {
+	if _, err := q.Exec(`DELETE FROM model_annotation_citations WHERE model_annotation_id = $1`, modelAnnotationID); err != nil {
+		return err
+	}
+	for i, citation := range citations {
+		_, err := q.Exec(`
+			INSERT INTO model_annotation_citations
+			(model_annotation_id, position, review_id, start_offset, end_offset, quote)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, modelAnnotationID, i+1, citation.ReviewID, citation.Start, citation.End, citation.Quote)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadModel 读取模型标注的引用，按模型标注ID分组
+func LoadModel(q db.Querier, modelAnnotationIDs []int) (map[int][]models.EvidenceCitation, error) {
+	citations := map[int][]models.EvidenceCitation{}
+	if len(modelAnnotationIDs) == 0 {
+		return citations, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT c.model_annotation_id, `+citationColumns+`
+		FROM model_annotation_citations c JOIN reviews r ON r.id = c.review_id
+		WHERE c.model_annotation_id = ANY($1)
+		ORDER BY c.model_annotation_id, c.position
+	`, pq.Array(modelAnnotationIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		citation, err := scanCitation(rows, &id)
+		if err != nil {
+			return nil, err
+		}
+		citations[id] = append(citations[id], citation)
+	}
+	return citations, rows.Err()
+}
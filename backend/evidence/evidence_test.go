@@ -0,0 +1,153 @@
+package evidence
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+const (
+	asciiText = "Very kind doctor."    // 17个字符
+	cjkText   = "医生很耐心，解释得很清楚👍 Great!" // 20个字符，UTF-8为47字节
+)
+
+var (
+	testByID = map[int]review{
+		10: {ID: 10, Index: 0, Text: asciiText},
+		11: {ID: 11, Index: 1, Text: cjkText},
+		12: {ID: 12, Index: 0, Text: "no review_index"}, // review_index为NULL的旧评论只能按ID引用
+	}
+	testByIndex = map[int]review{0: testByID[10], 1: testByID[11]}
+)
+
+func TestCheckCitation(t *testing.T) {
+	tests := []struct {
+		name     string
+		citation models.EvidenceCitation
+		want     models.EvidenceCitation
+		reason   string // 不为空时期望校验失败
+	}{
+		{
+			name:     "ascii span fills quote and index",
+			citation: models.EvidenceCitation{ReviewID: 10, Start: 5, End: 9},
+			want:     models.EvidenceCitation{ReviewID: 10, ReviewIndex: 0, Start: 5, End: 9, Quote: "kind"},
+		},
+		{
+			name:     "end at text length",
+			citation: models.EvidenceCitation{ReviewID: 10, Start: 10, End: 17},
+			want:     models.EvidenceCitation{ReviewID: 10, Start: 10, End: 17, Quote: "doctor."},
+		},
+		{
+			name:     "end past text length",
+			citation: models.EvidenceCitation{ReviewID: 10, Start: 10, End: 18},
+			reason:   "span [10, 18) is outside the review text (17 characters)",
+		},
+		{name: "negative start", citation: models.EvidenceCitation{ReviewID: 10, Start: -1, End: 3}, reason: "outside the review text"},
+		{name: "empty span", citation: models.EvidenceCitation{ReviewID: 10, Start: 4, End: 4}, reason: "outside the review text"},
+		{
+			// 偏移按字符而不是字节计算
+			name:     "cjk offsets count characters",
+			citation: models.EvidenceCitation{ReviewID: 11, Start: 2, End: 5},
+			want:     models.EvidenceCitation{ReviewID: 11, ReviewIndex: 1, Start: 2, End: 5, Quote: "很耐心"},
+		},
+		{
+			name:     "emoji is one character",
+			citation: models.EvidenceCitation{ReviewID: 11, Start: 12, End: 13},
+			want:     models.EvidenceCitation{ReviewID: 11, ReviewIndex: 1, Start: 12, End: 13, Quote: "👍"},
+		},
+		{
+			name:     "cjk end at text length",
+			citation: models.EvidenceCitation{ReviewID: 11, Start: 14, End: 20, Quote: "Great!"},
+			want:     models.EvidenceCitation{ReviewID: 11, ReviewIndex: 1, Start: 14, End: 20, Quote: "Great!"},
+		},
+		{
+			name:     "cjk byte length is not the limit",
+			citation: models.EvidenceCitation{ReviewID: 11, Start: 14, End: 21},
+			reason:   "(20 characters)",
+		},
+		{
+			name:     "matching quote",
+			citation: models.EvidenceCitation{ReviewID: 10, Start: 5, End: 9, Quote: "kind"},
+			want:     models.EvidenceCitation{ReviewID: 10, Start: 5, End: 9, Quote: "kind"},
+		},
+		{
+			name:     "quote mismatch",
+			citation: models.EvidenceCitation{ReviewID: 10, Start: 5, End: 9, Quote: "kind "},
+			reason:   `quote does not match the review text "kind"`,
+		},
+		{
+			// review_id优先，review_index按评论更正
+			name:     "review id wins over index",
+			citation: models.EvidenceCitation{ReviewID: 11, ReviewIndex: 0, Start: 0, End: 2},
+			want:     models.EvidenceCitation{ReviewID: 11, ReviewIndex: 1, Start: 0, End: 2, Quote: "医生"},
+		},
+		{
+			name:     "fallback to review index",
+			citation: models.EvidenceCitation{ReviewIndex: 1, Start: 0, End: 2},
+			want:     models.EvidenceCitation{ReviewID: 11, ReviewIndex: 1, Start: 0, End: 2, Quote: "医生"},
+		},
+		{
+			name:     "stale flag is cleared",
+			citation: models.EvidenceCitation{ReviewID: 12, Start: 0, End: 2, Stale: true},
+			want:     models.EvidenceCitation{ReviewID: 12, Start: 0, End: 2, Quote: "no"},
+		},
+		{name: "unknown review id", citation: models.EvidenceCitation{ReviewID: 99, Start: 0, End: 1}, reason: "review does not belong to this physician"},
+		{name: "unknown review index", citation: models.EvidenceCitation{ReviewIndex: 5, Start: 0, End: 1}, reason: "review does not belong to this physician"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			citation := tt.citation
+			err := checkCitation(3, &citation, testByID, testByIndex)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if citation != tt.want {
+					t.Errorf("citation = %+v, want %+v", citation, tt.want)
+				}
+				return
+			}
+
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			if invalid.Index != 3 || !strings.Contains(invalid.Reason, tt.reason) {
+				t.Errorf("err = %+v, want index 3 and reason %q", invalid, tt.reason)
+			}
+		})
+	}
+}
+
+// TestOffsetsMatchPostgresSubstr citationColumns用substr判断引用是否过期，
+// 它对UTF-8文本按字符计数，必须与checkCitation的切片一致。需要TEST_DB_NAME
+func TestOffsetsMatchPostgresSubstr(t *testing.T) {
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME not set, skipping database test")
+	}
+	t.Setenv("DB_NAME", name)
+	db.InitDB()
+	defer db.CloseDB()
+
+	for _, span := range [][2]int{{0, 2}, {2, 5}, {11, 13}, {12, 13}, {14, 20}, {0, 20}} {
+		citation := models.EvidenceCitation{ReviewID: 11, Start: span[0], End: span[1]}
+		if err := checkCitation(0, &citation, testByID, testByIndex); err != nil {
+			t.Fatal(err)
+		}
+
+		var substr string
+		err := db.DB.QueryRow(`SELECT substr($1, $2 + 1, $3 - $2)`, cjkText, span[0], span[1]).Scan(&substr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if substr != citation.Quote {
+			t.Errorf("span %v: substr = %q, Go = %q", span, substr, citation.Quote)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxParams PostgreSQL单条语句的参数个数上限
+const maxParams = 65535
+
+// BatchSize 每条多行INSERT最多包含的行数
+var BatchSize = 500
+
+// execBatch 将多行数据拼成多行INSERT分批执行，返回实际写入的行数。
+// prefix 形如 "INSERT INTO t (a, b) VALUES"，suffix 为 ON CONFLICT 等子句。
+func execBatch(tx *sql.Tx, prefix, suffix string, rows [][]interface{}) (int64, error) {
+	var affected int64
+	err := eachBatch(rows, prefix, suffix, func(query string, args []interface{}) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		affected += n
+		return err
+	})
+	return affected, err
+}
+
+// queryBatch 与execBatch相同，suffix以RETURNING结尾，对返回的每一行调用scan
+func queryBatch(tx *sql.Tx, prefix, suffix string, rows [][]interface{}, scan func(*sql.Rows) error) error {
+	return eachBatch(rows, prefix, suffix, func(query string, args []interface{}) error {
+		result, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer result.Close()
+		for result.Next() {
+			if err := scan(result); err != nil {
+				return err
+			}
+		}
+		return result.Err()
+	})
+}
+
+// eachBatch 按参数个数上限把rows分批，为每批拼出多行INSERT语句及其参数
+func eachBatch(rows [][]interface{}, prefix, suffix string, run func(query string, args []interface{}) error) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns := len(rows[0])
+	size := BatchSize
+	if size*columns > maxParams {
+		size = maxParams / columns
+	}
+
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var query strings.Builder
+		query.WriteString(prefix)
+		args := make([]interface{}, 0, (end-start)*columns)
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				query.WriteString(",")
+			}
+			query.WriteString(" (")
+			for j, value := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, value)
+				fmt.Fprintf(&query, "$%d", len(args))
+			}
+			query.WriteString(")")
+		}
+		query.WriteString(" ")
+		query.WriteString(suffix)
+
+		if err := run(query.String(), args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
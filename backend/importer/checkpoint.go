@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Checkpoint 记录已成功提交的医生NPI，每行一个，用于中断后继续导入
+// 可被多个goroutine同时使用。只跳过打开时从文件读入的NPI，本次运行提交的NPI
+// 另外记录，输入中后面再出现同一NPI时照常导入
+type Checkpoint struct {
+	mu        sync.Mutex
+	loaded    map[int64]bool // 之前的运行中已导入，打开后不再修改
+	committed map[int64]bool // 本次运行中已提交
+	file      *os.File
+}
+
+// OpenCheckpoint 打开检查点文件；resume为false时清空已有记录重新开始
+func OpenCheckpoint(path string, resume bool) (*Checkpoint, error) {
+	cp := &Checkpoint{loaded: map[int64]bool{}, committed: map[int64]bool{}}
+
+	if resume {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			npi, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid NPI %q", path, i+1, line)
+			}
+			cp.loaded[npi] = true
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	cp.file = file
+	return cp, nil
+}
+
+// Done 该NPI是否已在之前的运行中导入，本次运行提交的NPI不算
+func (cp *Checkpoint) Done(npi int64) bool {
+	return cp.loaded[npi]
+}
+
+// Len 之前的运行中已导入的NPI数量
+func (cp *Checkpoint) Len() int {
+	return len(cp.loaded)
+}
+
+// Mark 记录一个本次运行提交的NPI并立即落盘；同一NPI只写入一次
+func (cp *Checkpoint) Mark(npi int64) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.loaded[npi] || cp.committed[npi] {
+		return nil
+	}
+	cp.committed[npi] = true
+	if _, err := fmt.Fprintln(cp.file, npi); err != nil {
+		return err
+	}
+	return cp.file.Sync()
+}
+
+// Close 关闭检查点文件
+func (cp *Checkpoint) Close() error {
+	return cp.file.Close()
+}
@@ -0,0 +1,468 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/reviewdoc"
+)
+
+// 导入的实体类型
+const (
+	EntityRecords          = "records" // 嵌套JSON记录：医生、评论和模型输出
+	EntityPhysicians       = "physicians"
+	EntityReviews          = "reviews"
+	EntityModelAnnotations = "model_annotations"
+)
+
+// entityFields 每种CSV实体可识别的字段，required为必需字段
+var entityFields = map[string]struct {
+	fields   []string
+	required []string
+}{
+	EntityPhysicians: {
+		fields: []string{"phy_id", "npi", "first_name", "last_name", "gender", "credential", "specialty",
+			"practice_zip5", "business_zip5", "biography_doc", "education_doc", "num_reviews",
+			"doc_name", "zipcode", "state", "region"},
+		required: []string{"npi"},
+	},
+	EntityReviews: {
+		fields:   []string{"npi", "review_index", "date", "source", "text"},
+		required: []string{"npi", "review_index", "date", "source", "text"},
+	},
+	EntityModelAnnotations: {
+		fields:   []string{"npi", "provider", "model", "trait", "score", "consistency", "sufficiency", "evidence"},
+		required: []string{"npi", "trait", "score"},
+	},
+}
+
+// ColumnMapping 列映射配置：实体 -> 字段 -> CSV表头，未配置的字段按同名表头匹配（忽略大小写）
+//
+//	{"physicians": {"npi": "NPI", "first_name": "FirstName"}}
+type ColumnMapping map[string]map[string]string
+
+// LoadColumnMapping 读取JSON格式的列映射配置，path为空时返回空配置
+func LoadColumnMapping(path string) (ColumnMapping, error) {
+	mapping := ColumnMapping{}
+	if path == "" {
+		return mapping, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for entity, fields := range mapping {
+		spec, ok := entityFields[entity]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown entity %q", path, entity)
+		}
+		for field := range fields {
+			if !contains(spec.fields, field) {
+				return nil, fmt.Errorf("%s: unknown %s field %q", path, entity, field)
+			}
+		}
+	}
+	return mapping, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// rowReader 逐行读取表格数据，第一行为表头；*csv.Reader和parquetRows都实现了它
+type rowReader interface {
+	Read() ([]string, error)
+}
+
+// csvTable 按字段名读取CSV行（Parquet文件也按同样的方式读取）
+type csvTable struct {
+	reader  rowReader
+	columns map[string]int // 字段 -> 列序号
+	line    int
+}
+
+func newCSVTable(r io.Reader, entity string, mapping ColumnMapping) (*csvTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return newTable(reader, entity, mapping)
+}
+
+// newTable 读取表头，按映射配置和实体字段确定列序号
+func newTable(reader rowReader, entity string, mapping ColumnMapping) (*csvTable, error) {
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	positions := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		positions[name] = i
+	}
+
+	spec := entityFields[entity]
+	table := &csvTable{reader: reader, columns: map[string]int{}, line: 1}
+	for _, field := range spec.fields {
+		column := field
+		if mapped, ok := mapping[entity][field]; ok {
+			column = mapped
+		}
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(column))]; ok {
+			table.columns[field] = i
+		}
+	}
+
+	for _, field := range spec.required {
+		if _, ok := table.columns[field]; !ok {
+			return nil, fmt.Errorf("missing required %s column %q", entity, field)
+		}
+	}
+	return table, nil
+}
+
+// csvRow 一行CSV数据及其行号
+type csvRow struct {
+	line   int
+	values []string
+	table  *csvTable
+}
+
+func (t *csvTable) next() (csvRow, error) {
+	values, err := t.reader.Read()
+	if err != nil {
+		return csvRow{}, err
+	}
+	t.line++
+	return csvRow{line: t.line, values: values, table: t}, nil
+}
+
+// get 返回字段值，字段未映射或该行缺列时返回空字符串
+func (r csvRow) get(field string) string {
+	i, ok := r.table.columns[field]
+	if !ok || i >= len(r.values) {
+		return ""
+	}
+	return strings.TrimSpace(r.values[i])
+}
+
+func (r csvRow) npi() (int64, error) {
+	npi, err := strconv.ParseInt(r.get("npi"), 10, 64)
+	if err != nil || npi == 0 {
+		return 0, fmt.Errorf("line %d: invalid npi %q", r.line, r.get("npi"))
+	}
+	return npi, nil
+}
+
+// parseFloat 空值视为0，"45342.0"之类的数字也可以解析
+func parseFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// physicianCSVSource 每行一位医生。已有医生只更新CSV中出现的列；
+// 同一NPI再次出现时作为失败的单元返回，避免不同worker导入的两行互相覆盖
+type physicianCSVSource struct {
+	table *csvTable
+	seen  map[int64]int // NPI -> 第一次出现的行号
+}
+
+func (s *physicianCSVSource) Next() (Unit, error) {
+	row, err := s.table.next()
+	if err != nil {
+		return Unit{}, err
+	}
+
+	npi, err := row.npi()
+	if err != nil {
+		return Unit{Err: err}, nil
+	}
+	if s.seen == nil {
+		s.seen = map[int64]int{}
+	}
+	if first, ok := s.seen[npi]; ok {
+		return Unit{NPI: npi, Err: fmt.Errorf("line %d: duplicate npi %d, first seen at line %d", row.line, npi, first)}, nil
+	}
+	s.seen[npi] = row.line
+
+	record := PhysicianRecord{
+		NPI:          npi,
+		FirstName:    row.get("first_name"),
+		LastName:     row.get("last_name"),
+		Gender:       row.get("gender"),
+		Credential:   row.get("credential"),
+		Specialty:    row.get("specialty"),
+		BiographyDoc: row.get("biography_doc"),
+		EducationDoc: row.get("education_doc"),
+		DocName:      row.get("doc_name"),
+		Zipcode:      row.get("zipcode"),
+		State:        row.get("state"),
+		Region:       row.get("region"),
+	}
+
+	for field, target := range map[string]*float64{
+		"practice_zip5": &record.PracticeZip5,
+		"business_zip5": &record.BusinessZip5,
+		"num_reviews":   &record.NumReviews,
+	} {
+		if *target, err = parseFloat(row.get(field)); err != nil {
+			return Unit{NPI: npi, Err: fmt.Errorf("line %d: invalid %s %q", row.line, field, row.get(field))}, nil
+		}
+	}
+	if value := row.get("phy_id"); value != "" {
+		phyID, err := parseFloat(value)
+		if err != nil {
+			return Unit{NPI: npi, Err: fmt.Errorf("line %d: invalid phy_id %q", row.line, value)}, nil
+		}
+		record.PhyID = int64(phyID)
+	}
+
+	columns := s.columns()
+	return Unit{NPI: npi, Name: record.DocName, Import: func(tx *sql.Tx, result *Result) error {
+		var err error
+		result.PhysicianID, result.Inserted, err = upsertPhysician(tx, record, columns)
+		if err != nil {
+			return fmt.Errorf("physician: %w", err)
+		}
+		return nil
+	}}, nil
+}
+
+// columns 返回CSV中出现的医生列，未出现的列不覆盖已有医生的值
+func (s *physicianCSVSource) columns() []string {
+	var columns []string
+	for _, column := range physicianColumns {
+		if _, ok := s.table.columns[column]; ok {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// groupedCSVSource 将同一NPI的相邻行合并为一个导入单元，文件必须按NPI分组（例如按NPI排序）。
+// 某个NPI的行结束后又再次出现时，再次出现的这组行作为失败的单元返回，不会导入
+type groupedCSVSource struct {
+	table   *csvTable
+	pending *csvRow
+	closed  map[int64]int // 已结束分组的NPI -> 该组第一行的行号
+	build   func(npi int64, rows []csvRow) Unit
+}
+
+func (s *groupedCSVSource) Next() (Unit, error) {
+	var rows []csvRow
+	var npi int64
+
+	for {
+		var row csvRow
+		if s.pending != nil {
+			row, s.pending = *s.pending, nil
+		} else {
+			var err error
+			row, err = s.table.next()
+			if err == io.EOF && len(rows) > 0 {
+				return s.close(npi, rows), nil
+			}
+			if err != nil {
+				return Unit{}, err
+			}
+		}
+
+		rowNPI, err := row.npi()
+		if err != nil {
+			if len(rows) > 0 {
+				s.pending = &row
+				return s.close(npi, rows), nil
+			}
+			return Unit{Err: err}, nil
+		}
+
+		if len(rows) > 0 && rowNPI != npi {
+			s.pending = &row
+			return s.close(npi, rows), nil
+		}
+		npi = rowNPI
+		rows = append(rows, row)
+	}
+}
+
+// close 结束一个分组；NPI此前已有分组时说明文件未按NPI分组，返回错误单元
+func (s *groupedCSVSource) close(npi int64, rows []csvRow) Unit {
+	if s.closed == nil {
+		s.closed = map[int64]int{}
+	}
+	if first, ok := s.closed[npi]; ok {
+		return Unit{NPI: npi, Err: fmt.Errorf("line %d: npi %d already appeared in a group starting at line %d; "+
+			"rows of the same npi must be adjacent, sort the file by npi", rows[0].line, npi, first)}
+	}
+	s.closed[npi] = rows[0].line
+	return s.build(npi, rows)
+}
+
+// reviewUnit 为已存在的医生导入评论
+func reviewUnit(npi int64, rows []csvRow) Unit {
+	return Unit{NPI: npi, Import: func(tx *sql.Tx, result *Result) error {
+		var err error
+		if result.PhysicianID, err = physicianByNPI(tx, npi); err != nil {
+			return err
+		}
+
+		var entries []reviewdoc.Entry
+		seen := map[int]bool{}
+		for _, row := range rows {
+			index, err := strconv.Atoi(row.get("review_index"))
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: invalid review_index %q", row.line, row.get("review_index")))
+				continue
+			}
+			if seen[index] {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate review_index %d", row.line, index))
+				continue
+			}
+			date, err := reviewdoc.ParseDate(row.get("date"))
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: invalid date %q", row.line, row.get("date")))
+				continue
+			}
+			if row.get("source") == "" || row.get("text") == "" {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: empty source or text", row.line))
+				continue
+			}
+			seen[index] = true
+			entries = append(entries, reviewdoc.Entry{
+				Index:  index,
+				Date:   date,
+				Source: row.get("source"),
+				Text:   row.get("text"),
+			})
+		}
+
+		if err := upsertReviews(tx, result, entries); err != nil {
+			return fmt.Errorf("reviews: %w", err)
+		}
+		return nil
+	}}
+}
+
+// modelAnnotationUnit 为已存在的医生导入模型标注
+func modelAnnotationUnit(options CSVOptions) func(npi int64, rows []csvRow) Unit {
+	return func(npi int64, rows []csvRow) Unit {
+		return Unit{NPI: npi, Import: func(tx *sql.Tx, result *Result) error {
+			var err error
+			if result.PhysicianID, err = physicianByNPI(tx, npi); err != nil {
+				return err
+			}
+
+			registered := map[string]models.Model{}
+			seen := map[string]bool{}
+			var outputs []modelOutput
+			for _, row := range rows {
+				provider, version := row.get("provider"), row.get("model")
+				if provider == "" && version == "" {
+					provider, version, _ = strings.Cut(options.DefaultModel, "/")
+				}
+				if provider == "" || version == "" {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: missing provider or model", row.line))
+					continue
+				}
+
+				trait := strings.ToLower(row.get("trait"))
+				if !models.IsValidTrait(trait) {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: invalid trait %q", row.line, row.get("trait")))
+					continue
+				}
+
+				key := provider + "/" + version
+				if options.OnlyDefaultModel && key != options.DefaultModel {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: skipped model %s", row.line, key))
+					continue
+				}
+				if seen[key+"/"+trait] {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate %s %s", row.line, key, trait))
+					continue
+				}
+				seen[key+"/"+trait] = true
+
+				model, ok := registered[key]
+				if !ok {
+					if model, err = registerModel(tx, provider, version); err != nil {
+						return fmt.Errorf("register %s: %w", key, err)
+					}
+					registered[key] = model
+				}
+
+				outputs = append(outputs, modelOutput{
+					Model: model,
+					Trait: trait,
+					Assessment: TraitAssessment{
+						Score:       row.get("score"),
+						Consistency: row.get("consistency"),
+						Sufficiency: row.get("sufficiency"),
+						Evidence:    row.get("evidence"),
+					},
+				})
+			}
+
+			written, err := insertModelAnnotations(tx, result, outputs, !options.KeepExisting)
+			if err != nil {
+				return fmt.Errorf("model annotations: %w", err)
+			}
+			// CSV中没有引用，覆盖的标注清除旧的引用
+			if err := replaceModelCitations(tx, result, written); err != nil {
+				return fmt.Errorf("citations: %w", err)
+			}
+			return nil
+		}}
+	}
+}
+
+// CSVOptions CSV导入选项
+type CSVOptions struct {
+	Mapping      ColumnMapping
+	DefaultModel string // provider/model，model_annotations的CSV中没有provider和model列时使用
+	KeepExisting bool   // 不覆盖已有的模型标注
+	// 只导入DefaultModel的行，其他模型的行作为警告跳过
+	OnlyDefaultModel bool
+}
+
+// NewCSVSource 根据实体类型创建CSV数据源
+func NewCSVSource(r io.Reader, entity string, options CSVOptions) (Source, error) {
+	table, err := newCSVTable(r, entity, options.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	return newTableSource(table, entity, options)
+}
+
+// newTableSource 按实体类型把表格行组织为导入单元
+func newTableSource(table *csvTable, entity string, options CSVOptions) (Source, error) {
+	switch entity {
+	case EntityPhysicians:
+		return &physicianCSVSource{table: table}, nil
+	case EntityReviews:
+		return &groupedCSVSource{table: table, build: reviewUnit}, nil
+	case EntityModelAnnotations:
+		if _, ok := table.columns["model"]; !ok && options.DefaultModel == "" {
+			return nil, fmt.Errorf("model_annotations needs provider and model columns or a default model")
+		}
+		return &groupedCSVSource{table: table, build: modelAnnotationUnit(options)}, nil
+	}
+	return nil, fmt.Errorf("unsupported entity %q", entity)
+}
@@ -0,0 +1,34 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPhysicianCSVUpdatesOnlyPresentColumns(t *testing.T) {
+	input := "NPI,first_name,last_name,specialty,zipcode\n" +
+		"1234567890,Ann,Lee,Cardiology,94110\n" +
+		"1234567891,Bob,Kim,Oncology,10001\n" +
+		"1234567890,Ann,Lee,Pediatrics,94110\n"
+	source, err := NewCSVSource(strings.NewReader(input), EntityPhysicians, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未出现的列（biography_doc、state等）不在更新列表中
+	want := []string{"first_name", "last_name", "specialty", "zipcode"}
+	if got := source.(*physicianCSVSource).columns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+
+	for i, wantErr := range []bool{false, false, true} {
+		unit, err := source.Next()
+		if err != nil {
+			t.Fatalf("unit %d: %v", i, err)
+		}
+		if (unit.Err != nil) != wantErr {
+			t.Errorf("unit %d: err = %v, want error %v", i, unit.Err, wantErr)
+		}
+	}
+}
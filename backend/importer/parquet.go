@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/phyreview_annotator/reviewdoc"
+)
+
+// parquetBatch 每次从Parquet文件读取的行数
+const parquetBatch = 256
+
+// NewParquetSource 根据实体类型创建Parquet数据源。列名、映射配置和取值规则与CSV相同，
+// 只支持扁平的列；Parquet需要随机读取，不能从标准输入读取
+func NewParquetSource(r io.ReaderAt, size int64, entity string, options CSVOptions) (Source, error) {
+	rows, err := newParquetRows(r, size)
+	if err != nil {
+		return nil, err
+	}
+	table, err := newTable(rows, entity, options.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	return newTableSource(table, entity, options)
+}
+
+// parquetRows 将Parquet行转换为与CSV相同的文本值，第一次Read返回列名作为表头
+type parquetRows struct {
+	reader  *parquet.Reader
+	header  []string
+	formats []func(parquet.Value) string // 列序号 -> 取值转换为文本
+	started bool
+
+	batch []parquet.Row
+	pos   int
+	n     int
+	err   error
+}
+
+func newParquetRows(r io.ReaderAt, size int64) (*parquetRows, error) {
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+
+	schema := file.Schema()
+	rows := &parquetRows{batch: make([]parquet.Row, parquetBatch)}
+	for _, path := range schema.Columns() {
+		leaf, _ := schema.Lookup(path...)
+		if len(path) != 1 || leaf.MaxRepetitionLevel > 0 {
+			return nil, fmt.Errorf("parquet column %q is nested or repeated, only flat columns are supported",
+				strings.Join(path, "."))
+		}
+		rows.header = append(rows.header, path[0])
+		rows.formats = append(rows.formats, parquetFormat(leaf.Node.Type()))
+	}
+	rows.reader = parquet.NewReader(file)
+	return rows, nil
+}
+
+func (r *parquetRows) Read() ([]string, error) {
+	if !r.started {
+		r.started = true
+		return r.header, nil
+	}
+
+	if r.pos == r.n {
+		if r.err != nil {
+			return nil, r.err
+		}
+		r.n, r.err = r.reader.ReadRows(r.batch)
+		r.pos = 0
+		if r.n == 0 {
+			if r.err == nil {
+				r.err = io.EOF
+			}
+			return nil, r.err
+		}
+	}
+
+	row := r.batch[r.pos]
+	r.pos++
+	values := make([]string, len(r.header))
+	for _, value := range row {
+		if column := value.Column(); !value.IsNull() && column >= 0 && column < len(values) {
+			values[column] = r.formats[column](value)
+		}
+	}
+	return values, nil
+}
+
+// parquetFormat 按列的物理类型和逻辑类型选择文本格式：
+// 日期和时间戳转换为评论时间的格式，整数和浮点数不使用科学计数法
+func parquetFormat(t parquet.Type) func(parquet.Value) string {
+	logical := t.LogicalType()
+	switch t.Kind() {
+	case parquet.Boolean:
+		return func(v parquet.Value) string { return strconv.FormatBool(v.Boolean()) }
+	case parquet.Int32:
+		if logical != nil && logical.Date != nil {
+			return func(v parquet.Value) string {
+				return time.Unix(int64(v.Int32())*86400, 0).UTC().Format("2006-01-02")
+			}
+		}
+		return func(v parquet.Value) string { return strconv.FormatInt(int64(v.Int32()), 10) }
+	case parquet.Int64:
+		if logical != nil && logical.Timestamp != nil {
+			unit := logical.Timestamp.Unit
+			return func(v parquet.Value) string {
+				var ts time.Time
+				switch {
+				case unit.Millis != nil:
+					ts = time.UnixMilli(v.Int64())
+				case unit.Micros != nil:
+					ts = time.UnixMicro(v.Int64())
+				default:
+					ts = time.Unix(0, v.Int64())
+				}
+				return ts.UTC().Format(reviewdoc.DateLayout)
+			}
+		}
+		return func(v parquet.Value) string { return strconv.FormatInt(v.Int64(), 10) }
+	case parquet.Int96:
+		// 旧版Spark/Hive的时间戳：当天的纳秒数和儒略日
+		return func(v parquet.Value) string {
+			i := v.Int96()
+			days := int64(i[2]) - 2440588
+			nanos := int64(i[1])<<32 | int64(i[0])
+			return time.Unix(days*86400, nanos).UTC().Format(reviewdoc.DateLayout)
+		}
+	case parquet.Float:
+		return func(v parquet.Value) string { return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32) }
+	case parquet.Double:
+		return func(v parquet.Value) string { return strconv.FormatFloat(v.Double(), 'f', -1, 64) }
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return func(v parquet.Value) string { return string(v.ByteArray()) }
+	}
+	return func(v parquet.Value) string { return v.String() }
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetReview struct {
+	NPI         int64     `parquet:"NPI"`
+	ReviewIndex int32     `parquet:"review_index"`
+	Date        time.Time `parquet:"date,timestamp(microsecond)"`
+	Source      string    `parquet:"source"`
+	Text        *string   `parquet:"text,optional"`
+	Rating      float64   `parquet:"rating"`
+}
+
+func TestParquetRowsMatchCSVText(t *testing.T) {
+	text := "Great doctor"
+	var buf bytes.Buffer
+	err := parquet.Write(&buf, []parquetReview{
+		{NPI: 1234567890, ReviewIndex: 0, Date: time.Date(2009, 4, 3, 14, 53, 21, 0, time.UTC), Source: "Vitals", Text: &text, Rating: 4.5},
+		{NPI: 1234567890, ReviewIndex: 1, Date: time.Date(2010, 1, 2, 0, 0, 0, 0, time.UTC), Source: "Healthgrades", Rating: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := newParquetRows(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 表头按忽略大小写匹配，review_index通过映射改名
+	table, err := newTable(rows, EntityReviews, ColumnMapping{EntityReviews: {"review_index": "REVIEW_INDEX"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []map[string]string{
+		{"npi": "1234567890", "review_index": "0", "date": "2009-04-03 14:53:21", "source": "Vitals", "text": "Great doctor"},
+		{"npi": "1234567890", "review_index": "1", "date": "2010-01-02 00:00:00", "source": "Healthgrades", "text": ""},
+	}
+	for i, fields := range want {
+		row, err := table.next()
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		if row.line != i+2 {
+			t.Errorf("row %d: line = %d, want %d", i, row.line, i+2)
+		}
+		for field, value := range fields {
+			if got := row.get(field); got != value {
+				t.Errorf("row %d: %s = %q, want %q", i, field, got, value)
+			}
+		}
+	}
+	if _, err := table.next(); err != io.EOF {
+		t.Fatalf("after last row: err = %v, want io.EOF", err)
+	}
+}
+
+func TestParquetRejectsNestedColumns(t *testing.T) {
+	type nested struct {
+		NPI  int64    `parquet:"npi"`
+		Tags []string `parquet:"tags,list"`
+	}
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, []nested{{NPI: 1, Tags: []string{"a"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newParquetRows(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Fatal("expected an error for a nested column")
+	}
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// Options 导入流程选项
+type Options struct {
+	Workers    int         // 并发导入的worker数量
+	DryRun     bool        // 执行全部写入后回滚
+	Checkpoint *Checkpoint // 为nil时不跳过也不记录
+}
+
+// Summary 导入结束时的统计
+type Summary struct {
+	Inserted    int
+	Updated     int
+	Skipped     int
+	Failed      int
+	Warnings    int
+	Reviews     int
+	Annotations int
+	Tasks       int
+	Failures    []string
+}
+
+// outcome 单个导入单元的处理结果，由worker交给汇总goroutine
+type outcome struct {
+	Seq     int
+	NPI     int64
+	DocName string
+	Result  Result
+	Err     error
+	Skipped bool
+}
+
+// pending 等待worker导入的单元
+type pending struct {
+	job  outcome
+	unit Unit
+}
+
+// Run 从数据源逐个读取导入单元，交给worker池并发导入，每个单元一个事务
+func Run(conn *sql.DB, source Source, options Options) Summary {
+	workers := options.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	checkpoint := options.Checkpoint
+
+	units := make(chan pending, workers*2)
+	results := make(chan outcome, workers*2)
+	var readErr error
+	var readCount int
+
+	// 读取导入单元；无法解析、之前的运行中已导入或缺少NPI的直接跳过
+	var wg sync.WaitGroup
+	go func() {
+		defer close(units)
+		for seq := 1; ; seq++ {
+			unit, err := source.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				readErr = err
+				return
+			}
+			readCount = seq
+
+			job := outcome{Seq: seq, NPI: unit.NPI, DocName: unit.Name}
+			if unit.Err != nil {
+				job.Err = unit.Err
+				results <- job
+				continue
+			}
+			if unit.NPI == 0 {
+				log.Printf("Skipping record #%d (%s): missing NPI", seq, unit.Name)
+				job.Skipped = true
+				results <- job
+				continue
+			}
+			if checkpoint != nil && checkpoint.Done(unit.NPI) {
+				job.Skipped = true
+				results <- job
+				continue
+			}
+
+			units <- pending{job, unit}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range units {
+				p.job.Result, p.job.Err = ImportUnit(conn, p.unit, options.DryRun)
+				results <- p.job
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var summary Summary
+	for o := range results {
+		summary.add(o)
+		if o.Err == nil && !o.Skipped && checkpoint != nil {
+			if err := checkpoint.Mark(o.NPI); err != nil {
+				log.Fatal("Failed to write checkpoint:", err)
+			}
+		}
+	}
+
+	if readErr != nil {
+		log.Printf("Input stopped after %d records: %v", readCount, readErr)
+		summary.Failed++
+		summary.Failures = append(summary.Failures, fmt.Sprintf("input: %v", readErr))
+	}
+	return summary
+}
+
+// add 汇总一个导入单元的处理结果并输出日志
+func (s *Summary) add(o outcome) {
+	if o.Skipped {
+		s.Skipped++
+		return
+	}
+
+	for _, warning := range o.Result.Warnings {
+		log.Printf("Warning: record #%d (NPI %d): %s", o.Seq, o.NPI, warning)
+	}
+	s.Warnings += len(o.Result.Warnings)
+
+	if o.Err != nil {
+		log.Printf("Failed record #%d (NPI %d, %s), rolled back: %v", o.Seq, o.NPI, o.DocName, o.Err)
+		s.Failed++
+		s.Failures = append(s.Failures, fmt.Sprintf("record #%d NPI %d (%s): %v", o.Seq, o.NPI, o.DocName, o.Err))
+		return
+	}
+
+	if o.Result.Inserted {
+		s.Inserted++
+	} else {
+		s.Updated++
+	}
+	s.Reviews += o.Result.Reviews
+	s.Annotations += o.Result.Annotations
+	s.Tasks += o.Result.Tasks
+	log.Printf("Imported record #%d: %s (NPI %d), %d reviews, %d model annotations",
+		o.Seq, o.DocName, o.NPI, o.Result.Reviews, o.Result.Annotations)
+}
+
+// Print 输出导入汇总
+func (s Summary) Print(dryRun bool) {
+	if dryRun {
+		log.Println("Dry run: all changes were rolled back")
+	}
+	log.Printf("Import summary: %d inserted, %d updated, %d skipped, %d failed, %d warnings",
+		s.Inserted, s.Updated, s.Skipped, s.Failed, s.Warnings)
+	log.Printf("Written: %d reviews, %d model annotations", s.Reviews, s.Annotations)
+	if s.Tasks > 0 {
+		log.Printf("Queued re-evaluation on %d tasks", s.Tasks)
+	}
+	for _, failure := range s.Failures {
+		log.Printf("  %s", failure)
+	}
+}
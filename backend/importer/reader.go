@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// RecordReader 流式读取医生记录，支持JSON数组和JSONL（每行一个对象），内存占用与文件大小无关
+type RecordReader struct {
+	decoder *json.Decoder
+	array   bool
+	started bool
+	count   int
+}
+
+// NewRecordReader 根据第一个非空白字符判断格式：'[' 为JSON数组，'{' 为JSONL
+func NewRecordReader(r io.Reader) (*RecordReader, error) {
+	buffered := bufio.NewReaderSize(r, 1<<20)
+	first, err := peekNonSpace(buffered)
+	if err != nil {
+		return nil, err
+	}
+	if first != '[' && first != '{' {
+		return nil, fmt.Errorf("unexpected %q at start of input, expected a JSON array or JSONL objects", first)
+	}
+
+	return &RecordReader{
+		decoder: json.NewDecoder(buffered),
+		array:   first == '[',
+	}, nil
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b)) && b != 0xEF && b != 0xBB && b != 0xBF { // 跳过空白和UTF-8 BOM
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+// Next 读取下一条记录的原始JSON，读完时返回io.EOF。
+// 语法错误无法恢复，直接返回错误；字段类型错误由调用方在Decode时逐条处理。
+func (rr *RecordReader) Next() (json.RawMessage, error) {
+	if rr.array && !rr.started {
+		if _, err := rr.decoder.Token(); err != nil { // 读取开头的 '['
+			return nil, err
+		}
+		rr.started = true
+	}
+
+	if rr.array && !rr.decoder.More() {
+		if _, err := rr.decoder.Token(); err != nil { // 读取结尾的 ']'
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := rr.decoder.Decode(&raw); err != nil {
+		if err == io.EOF && rr.array {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != io.EOF {
+			err = fmt.Errorf("record %d: %w", rr.count+1, err)
+		}
+		return nil, err
+	}
+	rr.count++
+	return raw, nil
+}
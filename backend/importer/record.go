@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/phyreview_annotator/modelregistry"
+	"github.com/phyreview_annotator/models"
+)
+
+// JSON数据结构
+type PhysicianRecord struct {
+	PhyID        int64   `json:"PhyID"`
+	NPI          int64   `json:"NPI"`
+	FirstName    string  `json:"FirstName"`
+	LastName     string  `json:"LastName"`
+	Gender       string  `json:"Gender"`
+	Credential   string  `json:"Credential"`
+	Specialty    string  `json:"Specialty"`
+	PracticeZip5 float64 `json:"PracticeZip5"`
+	BusinessZip5 float64 `json:"BusinessZip5"`
+	BiographyDoc string  `json:"biography_doc"`
+	EducationDoc string  `json:"education_doc"`
+	NumReviews   float64 `json:"num_reviews"`
+	ReviewDoc    string  `json:"review_doc"`
+	DocName      string  `json:"DocName"`
+	Zipcode      string  `json:"zipcode"`
+	State        string  `json:"state"`
+	Region       string  `json:"Region"`
+
+	// AI模型输出：字段名 output_<provider>_<model> -> 输出
+	Outputs map[string]ModelOutputs `json:"-"`
+}
+
+// UnmarshalJSON 解析固定字段，并收集所有 output_<provider>_<model> 字段
+func (r *PhysicianRecord) UnmarshalJSON(data []byte) error {
+	type plain PhysicianRecord
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	r.Outputs = map[string]ModelOutputs{}
+	for key, value := range fields {
+		if !strings.HasPrefix(key, modelregistry.OutputPrefix) || string(value) == "null" {
+			continue
+		}
+		var outputs ModelOutputs
+		if err := json.Unmarshal(value, &outputs); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		r.Outputs[key] = outputs
+	}
+	return nil
+}
+
+type ModelOutputs struct {
+	Openness          TraitAssessment `json:"Openness"`
+	Conscientiousness TraitAssessment `json:"Conscientiousness"`
+	Extraversion      TraitAssessment `json:"Extraversion"`
+	Agreeableness     TraitAssessment `json:"Agreeableness"`
+	Neuroticism       TraitAssessment `json:"Neuroticism"`
+}
+
+type TraitAssessment struct {
+	Consistency string                    `json:"consistency"`
+	Evidence    string                    `json:"evidence"`
+	Score       string                    `json:"score"`
+	Sufficiency string                    `json:"sufficiency"`
+	Citations   []models.EvidenceCitation `json:"citations"` // 可选，按review_index引用评论片段
+}
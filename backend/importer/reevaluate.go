@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/workflow"
+)
+
+// reevaluateSource 包装数据源：医生导入了新的模型标注后，把该医生已经开始的任务
+// 退回机器评价阶段，让标注人评价新模型的输出
+type reevaluateSource struct {
+	source    Source
+	changedBy string
+	reason    string
+}
+
+// QueueReevaluation 返回在导入新模型标注后重新打开相关任务的数据源。
+// 已完成的任务转为reopened；写入了新模型标注且已完成人类标注的trait清除机器评价和回顾进度，
+// 人类标注和已有的机器评价保持不变（有进度记录时workflow.CurrentStage只看进度标记）
+func QueueReevaluation(source Source, changedBy, reason string) Source {
+	return &reevaluateSource{source: source, changedBy: changedBy, reason: reason}
+}
+
+func (s *reevaluateSource) Next() (Unit, error) {
+	unit, err := s.source.Next()
+	if err != nil || unit.Import == nil {
+		return unit, err
+	}
+
+	importUnit := unit.Import
+	unit.Import = func(tx *sql.Tx, result *Result) error {
+		if err := importUnit(tx, result); err != nil {
+			return err
+		}
+		if len(result.Traits) == 0 {
+			return nil
+		}
+		tasks, err := reopenTasks(tx, result.PhysicianID, result.Traits, s.changedBy, s.reason)
+		result.Tasks += tasks
+		return err
+	}
+	return unit, nil
+}
+
+// reopenTasks 重新打开医生已开始的任务，只把traits退回机器评价阶段，返回受影响的任务数
+func reopenTasks(tx *sql.Tx, physicianID int, traits []string, changedBy, reason string) (int, error) {
+	rows, err := tx.Query(`
+		SELECT id, status FROM tasks
+		WHERE physician_id = $1 AND status IN ($2, $3, $4)
+		ORDER BY id
+	`, physicianID, models.TaskStatusInProgress, models.TaskStatusCompleted, models.TaskStatusReopened)
+	if err != nil {
+		return 0, err
+	}
+
+	type task struct {
+		id     int
+		status string
+	}
+	var tasks []task
+	for rows.Next() {
+		var t task
+		if err := rows.Scan(&t.id, &t.status); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tasks = append(tasks, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, t := range tasks {
+		if t.status == models.TaskStatusCompleted {
+			err := workflow.TransitionTask(tx, t.id, physicianID, models.TaskStatusReopened, changedBy, reason)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		_, err := tx.Exec(`
+			UPDATE trait_progress
+			SET machine_evaluation_completed = false, review_completed = false, timestamp = CURRENT_TIMESTAMP
+			WHERE physician_id = $1 AND task_id = $2 AND human_annotation_completed = true AND trait = ANY($3)
+		`, physicianID, t.id, pq.Array(traits))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(tasks), nil
+}
@@ -0,0 +1,229 @@
+package importer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/auth"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/importer"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/routes"
+	"github.com/phyreview_annotator/workflow"
+)
+
+// openTestDB 连接TEST_DB_NAME指定的数据库并执行全部迁移，未配置时跳过。
+// 测试会写入数据，必须使用单独的测试库，其余连接参数与服务相同（DB_HOST等）
+func openTestDB(t *testing.T) {
+	t.Helper()
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME not set, skipping database test")
+	}
+	t.Setenv("DB_NAME", name)
+
+	db.InitDB()
+	t.Cleanup(db.CloseDB)
+	if err := db.MigrateUp(db.DB, nil); err != nil {
+		t.Fatal("migrate:", err)
+	}
+}
+
+// modelRunRecord 构造一条只含一个模型输出的嵌套JSON记录，五个trait输出相同
+func modelRunRecord(npi int64, outputKey string) string {
+	assessment := map[string]string{"score": "High", "consistency": "High", "sufficiency": "High", "evidence": "test"}
+	outputs := map[string]interface{}{}
+	for _, trait := range []string{"Openness", "Conscientiousness", "Extraversion", "Agreeableness", "Neuroticism"} {
+		outputs[trait] = assessment
+	}
+	record, _ := json.Marshal(map[string]interface{}{
+		"NPI":     npi,
+		"DocName": "Reevaluate Test",
+		outputKey: outputs,
+	})
+	return string(record)
+}
+
+func runImport(t *testing.T, source importer.Source, err error) importer.Summary {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := importer.Run(db.DB, source, importer.Options{Workers: 1})
+	if summary.Failed > 0 {
+		t.Fatalf("import failed: %v", summary.Failures)
+	}
+	return summary
+}
+
+// TestReevaluateReopensMachineEvaluation 对应 import-model-run -reevaluate：
+// 已完成的任务导入新模型输出后应回到机器评价阶段，并能提交对新模型的评价
+func TestReevaluateReopensMachineEvaluation(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	suffix := time.Now().UnixNano() % 1000000000
+	npi := 9000000000 + suffix
+	evaluator := fmt.Sprintf("reevaluate-%d", suffix)
+	trait := models.TraitOpenness
+	const taskID = 1
+
+	_, err := db.DB.Exec(`INSERT INTO users (username, password_hash, role) VALUES ($1, 'x', $2)`,
+		evaluator, models.RoleAnnotator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 医生和第一个模型的输出
+	source, err := importer.NewJSONSource(strings.NewReader(modelRunRecord(npi, "output_openai_gpt-4.1")))
+	runImport(t, source, err)
+
+	var physicianID, oldAnnotationID int
+	err = db.DB.QueryRow(`
+		SELECT p.id, m.id FROM physicians p JOIN model_annotations m ON m.physician_id = p.id
+		WHERE p.npi = $1 AND m.trait = $2
+	`, npi, trait).Scan(&physicianID, &oldAnnotationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 该trait已走完全部阶段的已完成任务
+	for _, statement := range []string{
+		`INSERT INTO tasks (id, physician_id, status, assigned_to) VALUES ($1, $2, 'completed', $3)`,
+		`INSERT INTO human_annotations (physician_id, evaluator, task_id, trait, score, consistency, sufficiency)
+		 VALUES ($2, $3, $1, $4, 3, 3, 3)`,
+		`INSERT INTO trait_progress (physician_id, task_id, evaluator, trait,
+		 human_annotation_completed, machine_evaluation_completed, review_completed)
+		 VALUES ($2, $1, $3, $4, true, true, true)`,
+		`INSERT INTO machine_annotation_evaluation (model_annotation_id, physician_id, task_id, evaluator, trait, model_name, rating)
+		 VALUES ($5, $2, $1, $3, $4, 'gpt-4.1', 'thumb_up')`,
+	} {
+		if _, err := db.DB.Exec(statement, taskID, physicianID, evaluator, trait, oldAnnotationID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// import-model-run -model anthropic/claude-test -reevaluate
+	source, err = importer.NewModelRunJSONSource(strings.NewReader(modelRunRecord(npi, "output_anthropic_claude-test")),
+		"anthropic", "claude-test")
+	summary := runImport(t, importer.QueueReevaluation(source, "import-model-run", "new model run"), err)
+	if summary.Tasks != 1 {
+		t.Fatalf("reopened %d tasks, want 1", summary.Tasks)
+	}
+
+	var status string
+	if err := db.DB.QueryRow(`SELECT status FROM tasks WHERE id = $1 AND physician_id = $2`, taskID, physicianID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != models.TaskStatusReopened {
+		t.Fatalf("task status = %s, want %s", status, models.TaskStatusReopened)
+	}
+
+	stage, err := workflow.CurrentStage(db.DB, physicianID, taskID, evaluator, trait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage != models.StageMachineEvaluation {
+		t.Fatalf("stage after reevaluate = %s, want %s", stage, models.StageMachineEvaluation)
+	}
+
+	var newAnnotationID int
+	err = db.DB.QueryRow(`
+		SELECT id FROM model_annotations WHERE physician_id = $1 AND trait = $2 AND id <> $3
+	`, physicianID, trait, oldAnnotationID).Scan(&newAnnotationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := auth.IssueToken(evaluator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal([]models.MachineAnnotationEvaluation{
+		{ModelAnnotationID: newAnnotationID, ModelName: "claude-test", Rating: "just_soso"},
+	})
+	url := fmt.Sprintf("/api/physician/%d/task/%d/trait/%s/machine-evaluation", npi, taskID, trait)
+	request := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	routes.SetupRouter().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("submit machine evaluation: %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	stage, err = workflow.CurrentStage(db.DB, physicianID, taskID, evaluator, trait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage != models.StageReviewAndModify {
+		t.Fatalf("stage after machine evaluation = %s, want %s", stage, models.StageReviewAndModify)
+	}
+}
+
+// TestReevaluateOnlyReopensImportedTraits 新模型只输出了一个trait时，其他trait的进度保持不变
+func TestReevaluateOnlyReopensImportedTraits(t *testing.T) {
+	openTestDB(t)
+
+	suffix := time.Now().UnixNano() % 1000000000
+	npi := 9100000000 + suffix
+	evaluator := fmt.Sprintf("reevaluate-trait-%d", suffix)
+	const taskID = 1
+
+	_, err := db.DB.Exec(`INSERT INTO users (username, password_hash, role) VALUES ($1, 'x', $2)`,
+		evaluator, models.RoleAnnotator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := importer.NewJSONSource(strings.NewReader(modelRunRecord(npi, "output_openai_gpt-4.1")))
+	runImport(t, source, err)
+
+	var physicianID int
+	if err := db.DB.QueryRow(`SELECT id FROM physicians WHERE npi = $1`, npi).Scan(&physicianID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.DB.Exec(`INSERT INTO tasks (id, physician_id, status, assigned_to) VALUES ($1, $2, 'completed', $3)`,
+		taskID, physicianID, evaluator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trait := range []string{models.TraitOpenness, models.TraitExtraversion} {
+		_, err := db.DB.Exec(`
+			INSERT INTO trait_progress (physician_id, task_id, evaluator, trait,
+			human_annotation_completed, machine_evaluation_completed, review_completed)
+			VALUES ($1, $2, $3, $4, true, true, true)
+		`, physicianID, taskID, evaluator, trait)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// import-model-run -model anthropic/claude-test -input one-trait.csv -reevaluate
+	input := fmt.Sprintf("npi,trait,score\n%d,extraversion,High\n", npi)
+	source, err = importer.NewCSVSource(strings.NewReader(input), importer.EntityModelAnnotations,
+		importer.CSVOptions{DefaultModel: "anthropic/claude-test", KeepExisting: true})
+	runImport(t, importer.QueueReevaluation(source, "import-model-run", "new model run"), err)
+
+	for trait, want := range map[string]bool{models.TraitOpenness: true, models.TraitExtraversion: false} {
+		var machine, review bool
+		err := db.DB.QueryRow(`
+			SELECT machine_evaluation_completed, review_completed FROM trait_progress
+			WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+		`, physicianID, taskID, evaluator, trait).Scan(&machine, &review)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if machine != want || review != want {
+			t.Errorf("%s: machine evaluation %v, review %v, want both %v", trait, machine, review, want)
+		}
+	}
+}
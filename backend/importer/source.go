@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/phyreview_annotator/modelregistry"
+)
+
+// Unit 一个导入单元：同一位医生的数据，在一个事务中导入
+type Unit struct {
+	NPI    int64
+	Name   string
+	Err    error // 记录本身无法解析时不为nil，Import为nil
+	Import func(tx *sql.Tx, result *Result) error
+}
+
+// Source 导入数据源，读完时返回io.EOF；其他错误表示输入无法继续读取
+type Source interface {
+	Next() (Unit, error)
+}
+
+// jsonSource 嵌套JSON记录（JSON数组或JSONL），每条记录包含医生、评论和模型输出
+type jsonSource struct {
+	reader *RecordReader
+	build  func(record PhysicianRecord) Unit
+}
+
+// NewJSONSource 导入完整记录：按NPI插入或更新医生，并导入评论和全部模型输出
+func NewJSONSource(r io.Reader) (Source, error) {
+	return newJSONSource(r, func(record PhysicianRecord) Unit {
+		return Unit{NPI: record.NPI, Name: record.DocName, Import: record.importInto}
+	})
+}
+
+// NewModelRunJSONSource 只导入记录中 output_<provider>_<version> 的模型输出，
+// 医生必须已存在，不修改医生、评论和已有的模型标注
+func NewModelRunJSONSource(r io.Reader, provider, version string) (Source, error) {
+	key := modelregistry.OutputPrefix + provider + "_" + version
+	return newJSONSource(r, func(record PhysicianRecord) Unit {
+		outputs, ok := record.Outputs[key]
+		if !ok {
+			return Unit{NPI: record.NPI, Name: record.DocName, Err: fmt.Errorf("record has no %s", key)}
+		}
+		return Unit{NPI: record.NPI, Name: record.DocName, Import: func(tx *sql.Tx, result *Result) error {
+			var err error
+			if result.PhysicianID, err = physicianByNPI(tx, record.NPI); err != nil {
+				return err
+			}
+			model, err := registerModel(tx, provider, version)
+			if err != nil {
+				return fmt.Errorf("register %s: %w", key, err)
+			}
+			written, err := insertModelAnnotations(tx, result, outputs.byTrait(model), false)
+			if err != nil {
+				return fmt.Errorf("model annotations: %w", err)
+			}
+			if err := replaceModelCitations(tx, result, written); err != nil {
+				return fmt.Errorf("citations: %w", err)
+			}
+			return nil
+		}}
+	})
+}
+
+func newJSONSource(r io.Reader, build func(record PhysicianRecord) Unit) (Source, error) {
+	reader, err := NewRecordReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonSource{reader: reader, build: build}, nil
+}
+
+func (s *jsonSource) Next() (Unit, error) {
+	raw, err := s.reader.Next()
+	if err != nil {
+		return Unit{}, err
+	}
+
+	var record PhysicianRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return Unit{Err: fmt.Errorf("decode: %w", err)}, nil
+	}
+	return s.build(record), nil
+}
@@ -0,0 +1,348 @@
+package importer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/phyreview_annotator/evidence"
+	"github.com/phyreview_annotator/modelregistry"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/reviewdoc"
+	"github.com/phyreview_annotator/scores"
+)
+
+// Result 单个导入单元（一位医生）的导入结果
+type Result struct {
+	PhysicianID int
+	Inserted    bool // false表示按NPI更新了已有医生
+	Reviews     int
+	Annotations int
+	Traits      []string // 写入了模型标注的trait
+	Tasks       int      // 因新模型标注重新打开的任务数
+	Warnings    []string // 被跳过的评论和无法解析的评分标签
+}
+
+// ModelRun 本次导入的模型运行信息，注册新模型时写入
+var ModelRun modelregistry.Run
+
+// ImportUnit 在一个事务中执行一个导入单元，任一步失败则整体回滚；
+// dryRun时执行全部写入后回滚，用于校验数据
+func ImportUnit(conn *sql.DB, unit Unit, dryRun bool) (Result, error) {
+	var result Result
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if err := unit.Import(tx, &result); err != nil {
+		return result, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	return result, tx.Commit()
+}
+
+// importInto 导入嵌套JSON记录：医生、评论和全部模型输出
+func (record PhysicianRecord) importInto(tx *sql.Tx, result *Result) error {
+	var err error
+	result.PhysicianID, result.Inserted, err = upsertPhysician(tx, record, physicianColumns)
+	if err != nil {
+		return fmt.Errorf("physician: %w", err)
+	}
+
+	// 解析评论文档：序号、时间、来源和正文
+	entries, malformed := reviewdoc.Parse(record.ReviewDoc)
+	for _, m := range malformed {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("malformed %s", m))
+	}
+	if err := upsertReviews(tx, result, entries); err != nil {
+		return fmt.Errorf("reviews: %w", err)
+	}
+
+	keys := make([]string, 0, len(record.Outputs))
+	for key := range record.Outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var outputs []modelOutput
+	for _, key := range keys {
+		provider, version, ok := modelregistry.ParseOutputKey(key)
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("cannot parse model key %q, expected output_<provider>_<model>", key))
+			continue
+		}
+		model, err := registerModel(tx, provider, version)
+		if err != nil {
+			return fmt.Errorf("register %s: %w", key, err)
+		}
+		outputs = append(outputs, record.Outputs[key].byTrait(model)...)
+	}
+
+	written, err := insertModelAnnotations(tx, result, outputs, true)
+	if err != nil {
+		return fmt.Errorf("model annotations: %w", err)
+	}
+	if err := replaceModelCitations(tx, result, written); err != nil {
+		return fmt.Errorf("citations: %w", err)
+	}
+	return nil
+}
+
+// replaceModelCitations 用本次写入的模型标注中的证据引用替换它们已有的引用，
+// 没有写入（保留已有值）的标注不受影响。引用按评论原文校验，不符时跳过该条标注的全部引用并记入警告
+func replaceModelCitations(tx *sql.Tx, result *Result, written []modelOutput) error {
+	for _, output := range written {
+		citations := output.Assessment.Citations
+		if len(citations) > 0 {
+			if err := evidence.Validate(tx, result.PhysicianID, citations); err != nil {
+				var invalid *evidence.ValidationError
+				if !errors.As(err, &invalid) {
+					return err
+				}
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("skipped citations of %s/%s: %v", output.Model.DisplayName, output.Trait, err))
+				citations = nil
+			}
+		}
+		if err := evidence.ReplaceModel(tx, output.AnnotationID, citations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// physicianColumns 已有医生按NPI更新时可覆盖的列，与CSV中医生的字段同名；
+// zipcode同时更新由它提取的zip3和zip2
+var physicianColumns = []string{"phy_id", "first_name", "last_name", "gender", "credential", "specialty",
+	"practice_zip5", "business_zip5", "biography_doc", "education_doc", "num_reviews",
+	"doc_name", "zipcode", "state", "region"}
+
+// upsertPhysician 按NPI插入或更新医生信息，inserted表示是否为新插入。
+// 医生已存在时只更新columns中的列，其余列保持原值
+func upsertPhysician(tx *sql.Tx, record PhysicianRecord, columns []string) (physicianID int, inserted bool, err error) {
+	// 从zipcode中提取zip3和zip2
+	zip3 := ""
+	zip2 := ""
+	if len(record.Zipcode) >= 3 {
+		zip3 = record.Zipcode[:3]
+	}
+	if len(record.Zipcode) >= 2 {
+		zip2 = record.Zipcode[:2]
+	}
+
+	// 没有可更新的列时仍需DO UPDATE，RETURNING才会返回已有的行
+	updates := []string{"npi = EXCLUDED.npi"}
+	for _, column := range columns {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		if column == "zipcode" {
+			updates = append(updates, "zip3 = EXCLUDED.zip3", "zip2 = EXCLUDED.zip2")
+		}
+	}
+
+	query := `
+		INSERT INTO physicians (phy_id, npi, first_name, last_name, gender, credential, specialty,
+								practice_zip5, business_zip5, biography_doc, education_doc, num_reviews,
+								doc_name, zip3, zip2, zipcode, state, region)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (npi) DO UPDATE SET ` + strings.Join(updates, ", ") + `
+		RETURNING id, (xmax = 0)`
+
+	err = tx.QueryRow(
+		query,
+		record.PhyID,
+		record.NPI,
+		record.FirstName,
+		record.LastName,
+		record.Gender,
+		record.Credential,
+		record.Specialty,
+		fmt.Sprintf("%.0f", record.PracticeZip5),
+		fmt.Sprintf("%.0f", record.BusinessZip5),
+		record.BiographyDoc,
+		record.EducationDoc,
+		int(record.NumReviews),
+		record.DocName,
+		zip3,
+		zip2,
+		record.Zipcode,
+		record.State,
+		record.Region,
+	).Scan(&physicianID, &inserted)
+	return physicianID, inserted, err
+}
+
+// physicianByNPI 查找已存在的医生，单独导入评论或模型标注时使用
+func physicianByNPI(tx *sql.Tx, npi int64) (int, error) {
+	var physicianID int
+	err := tx.QueryRow("SELECT id FROM physicians WHERE npi = $1", npi).Scan(&physicianID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("physician with NPI %d does not exist", npi)
+	}
+	return physicianID, err
+}
+
+// upsertReviews 按(physician_id, review_index)插入或更新评论
+func upsertReviews(tx *sql.Tx, result *Result, entries []reviewdoc.Entry) error {
+	rows := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, []interface{}{result.PhysicianID, entry.Index, entry.Source, entry.Date, entry.Text})
+	}
+
+	_, err := execBatch(tx,
+		`INSERT INTO reviews (physician_id, review_index, source, date, text) VALUES`,
+		`ON CONFLICT (physician_id, review_index) DO UPDATE SET
+			source = EXCLUDED.source, date = EXCLUDED.date, text = EXCLUDED.text`,
+		rows)
+	if err != nil {
+		return err
+	}
+	result.Reviews += len(rows)
+	return nil
+}
+
+// modelOutput 某个模型在某个trait上的一条输出
+type modelOutput struct {
+	Model        models.Model
+	Trait        string
+	Assessment   TraitAssessment
+	AnnotationID int // 写入后的model_annotations.id
+}
+
+// byTrait 将一个模型的五个trait输出展开为多条
+func (outputs ModelOutputs) byTrait(model models.Model) []modelOutput {
+	return []modelOutput{
+		{Model: model, Trait: models.TraitOpenness, Assessment: outputs.Openness},
+		{Model: model, Trait: models.TraitConscientiousness, Assessment: outputs.Conscientiousness},
+		{Model: model, Trait: models.TraitExtraversion, Assessment: outputs.Extraversion},
+		{Model: model, Trait: models.TraitAgreeableness, Assessment: outputs.Agreeableness},
+		{Model: model, Trait: models.TraitNeuroticism, Assessment: outputs.Neuroticism},
+	}
+}
+
+// registerModel 在事务中注册模型，并在第一次遇到时输出日志
+func registerModel(tx *sql.Tx, provider, version string) (models.Model, error) {
+	model, err := modelregistry.Register(tx, provider, version, ModelRun)
+	if err != nil {
+		return model, err
+	}
+	logModel(provider+"/"+version, model.DisplayName, model.ID)
+	return model, nil
+}
+
+// insertModelAnnotations 解析评分标签并按(physician_id, model_id, trait)写入模型标注，
+// 返回实际写入的输出及其标注ID。overwrite为false时保留已有标注，避免改动人类已经评价过的模型输出
+func insertModelAnnotations(tx *sql.Tx, result *Result, outputs []modelOutput, overwrite bool) ([]modelOutput, error) {
+	rows := make([][]interface{}, 0, len(outputs))
+	for _, output := range outputs {
+		modelName := output.Model.DisplayName
+		assessment := output.Assessment
+
+		// 解析评分标签，无法解析的只保存原始文本
+		normalized := map[string][3]interface{}{}
+		for dimension, raw := range map[string]string{
+			"score":       assessment.Score,
+			"consistency": assessment.Consistency,
+			"sufficiency": assessment.Sufficiency,
+		} {
+			score, err := scores.Parse(raw)
+			if err != nil {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("unparseable score %s/%s %s: %q", modelName, output.Trait, dimension, raw))
+			}
+			low, high, noEvidence := scores.Columns(score, err)
+			normalized[dimension] = [3]interface{}{low, high, noEvidence}
+		}
+
+		rows = append(rows, []interface{}{
+			result.PhysicianID,
+			output.Model.ID,
+			modelName,
+			output.Trait,
+			assessment.Score,
+			assessment.Consistency,
+			assessment.Sufficiency,
+			assessment.Evidence,
+			normalized["score"][0], normalized["score"][1], normalized["score"][2],
+			normalized["consistency"][0], normalized["consistency"][1], normalized["consistency"][2],
+			normalized["sufficiency"][0], normalized["sufficiency"][1], normalized["sufficiency"][2],
+		})
+	}
+
+	conflict := modelAnnotationUpsert
+	if !overwrite {
+		conflict = `ON CONFLICT (physician_id, model_id, trait) DO NOTHING`
+	}
+
+	byKey := map[string]int{}
+	for i, output := range outputs {
+		byKey[fmt.Sprintf("%d/%s", output.Model.ID, output.Trait)] = i
+	}
+	var written []modelOutput
+	err := queryBatch(tx, modelAnnotationInsert, conflict+` RETURNING id, model_id, trait`, rows, func(r *sql.Rows) error {
+		var id, modelID int
+		var trait string
+		if err := r.Scan(&id, &modelID, &trait); err != nil {
+			return err
+		}
+		output := outputs[byKey[fmt.Sprintf("%d/%s", modelID, trait)]]
+		output.AnnotationID = id
+		written = append(written, output)
+		if !contains(result.Traits, trait) {
+			result.Traits = append(result.Traits, trait)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if kept := len(rows) - len(written); kept > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d model annotations already exist and were kept", kept))
+	}
+	result.Annotations += len(written)
+	return written, nil
+}
+
+// modelAnnotationInsert 模型标注的多行INSERT前缀，与modelAnnotationUpsert配合使用
+const modelAnnotationInsert = `INSERT INTO model_annotations (physician_id, model_id, model_name, trait, score,
+	consistency, sufficiency, evidence,
+	score_low, score_high, score_no_evidence,
+	consistency_low, consistency_high, consistency_no_evidence,
+	sufficiency_low, sufficiency_high, sufficiency_no_evidence) VALUES`
+
+// modelAnnotationUpsert 同一医生、模型和trait的标注已存在时覆盖为新值
+const modelAnnotationUpsert = `ON CONFLICT (physician_id, model_id, trait) DO UPDATE SET
+	model_name = EXCLUDED.model_name, score = EXCLUDED.score,
+	consistency = EXCLUDED.consistency, sufficiency = EXCLUDED.sufficiency,
+	evidence = EXCLUDED.evidence,
+	score_low = EXCLUDED.score_low, score_high = EXCLUDED.score_high,
+	score_no_evidence = EXCLUDED.score_no_evidence,
+	consistency_low = EXCLUDED.consistency_low, consistency_high = EXCLUDED.consistency_high,
+	consistency_no_evidence = EXCLUDED.consistency_no_evidence,
+	sufficiency_low = EXCLUDED.sufficiency_low, sufficiency_high = EXCLUDED.sufficiency_high,
+	sufficiency_no_evidence = EXCLUDED.sufficiency_no_evidence`
+
+// loggedModels 已输出过注册信息的模型，多个worker共享
+var (
+	loggedModels   = map[string]bool{}
+	loggedModelsMu sync.Mutex
+)
+
+func logModel(key, displayName string, id int) {
+	loggedModelsMu.Lock()
+	defer loggedModelsMu.Unlock()
+	if loggedModels[key] {
+		return
+	}
+	loggedModels[key] = true
+	log.Printf("Model %s registered as %q (id %d)", key, displayName, id)
+}
@@ -21,6 +21,11 @@ func main() {
 	db.InitDB()
 	defer db.CloseDB()
 
+	// 检查数据库结构是否已执行全部迁移
+	if err := db.CheckSchema(db.DB); err != nil {
+		log.Fatalf("Schema check failed: %v (run `go run . up` in backend/cmd/migrate)", err)
+	}
+
 	// 设置路由
 	r := routes.SetupRouter()
 
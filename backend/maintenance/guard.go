@@ -0,0 +1,74 @@
+package maintenance
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ProductionEnv 标记生产环境的取值
+const ProductionEnv = "production"
+
+// 破坏性操作被拒绝的原因
+var (
+	ErrNotConfirmed = errors.New("destructive command needs -yes")
+	ErrProduction   = errors.New("refusing to run against a production database")
+	ErrNameMismatch = errors.New("typed database name does not match")
+)
+
+// IsProduction 数据库是否标记为生产环境：环境变量APP_ENV为production，
+// 或数据库设置了 ALTER DATABASE <name> SET app.environment = 'production'
+func IsProduction(conn *sql.DB) (bool, error) {
+	if strings.EqualFold(os.Getenv("APP_ENV"), ProductionEnv) {
+		return true, nil
+	}
+
+	var env sql.NullString
+	err := conn.QueryRow(`SELECT current_setting('app.environment', true)`).Scan(&env)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(env.String, ProductionEnv), nil
+}
+
+// DatabaseName 当前连接的数据库名
+func DatabaseName(conn *sql.DB) (string, error) {
+	var name string
+	err := conn.QueryRow(`SELECT current_database()`).Scan(&name)
+	return name, err
+}
+
+// Confirm 破坏性操作前的检查：必须传入-yes，数据库不能是生产环境，
+// 并且操作人需从in输入数据库名确认。action描述将要执行的操作，输出到out
+func Confirm(conn *sql.DB, yes bool, action string, in io.Reader, out io.Writer) error {
+	if !yes {
+		return ErrNotConfirmed
+	}
+
+	production, err := IsProduction(conn)
+	if err != nil {
+		return fmt.Errorf("check environment: %w", err)
+	}
+	if production {
+		return ErrProduction
+	}
+
+	name, err := DatabaseName(conn)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "This will %s database %q.\nType the database name to continue: ", action, name)
+	typed, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(typed) != name {
+		return ErrNameMismatch
+	}
+	return nil
+}
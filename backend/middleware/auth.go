@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phyreview_annotator/auth"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// 上下文中保存当前登录用户及其角色的键
+const (
+	evaluatorKey = "evaluator"
+	roleKey      = "role"
+)
+
+// RequireAuth 校验Bearer令牌，将登录用户注入上下文，
+// 并拒绝查询参数或请求体中evaluator与登录用户不一致的请求
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || token == header {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+			return
+		}
+
+		claims, err := auth.ParseToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "令牌无效或已过期"})
+			return
+		}
+
+		// 确认用户仍然存在并读取当前角色
+		var role string
+		err = db.DB.QueryRow("SELECT role FROM users WHERE username = $1", claims.Username).Scan(&role)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+				return
+			}
+			log.Println("查询用户错误:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "查询数据库出错"})
+			return
+		}
+
+		// 兼容旧客户端：username参数必须与登录用户一致
+		if username := c.Query("username"); username != "" && username != claims.Username {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "用户名与登录用户不一致"})
+			return
+		}
+
+		if c.Request.Body != nil && c.Request.ContentLength != 0 {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "读取请求体出错"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			if !evaluatorsMatch(body, claims.Username) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "evaluator与登录用户不一致"})
+				return
+			}
+		}
+
+		c.Set(evaluatorKey, claims.Username)
+		c.Set(roleKey, role)
+		c.Next()
+	}
+}
+
+// CurrentEvaluator 返回当前登录用户名
+func CurrentEvaluator(c *gin.Context) string {
+	return c.GetString(evaluatorKey)
+}
+
+// CurrentRole 返回当前登录用户的角色
+func CurrentRole(c *gin.Context) string {
+	return c.GetString(roleKey)
+}
+
+// RequireRole 仅允许指定角色访问，admin始终放行；需在RequireAuth之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := CurrentRole(c)
+		if role == models.RoleAdmin {
+			c.Next()
+			return
+		}
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "没有访问权限"})
+	}
+}
+
+// evaluatorsMatch 检查请求体（对象或对象数组）中的evaluator字段，
+// 缺省的evaluator视为匹配，由处理函数填充
+func evaluatorsMatch(body []byte, username string) bool {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// 非JSON请求体交由处理函数自行校验
+		return true
+	}
+
+	var objects []interface{}
+	switch v := payload.(type) {
+	case []interface{}:
+		objects = v
+	default:
+		objects = []interface{}{v}
+	}
+
+	for _, item := range objects {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, exists := obj["evaluator"]
+		if !exists || value == nil || value == "" {
+			continue
+		}
+		if evaluator, ok := value.(string); !ok || evaluator != username {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package modelregistry
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// OutputPrefix 导入数据中模型输出字段的前缀：output_<provider>_<model>
+const OutputPrefix = "output_"
+
+// knownDisplayNames 注册表出现之前导入代码中写死的显示名称，保持已有model_name不变
+var knownDisplayNames = map[string]string{
+	"openai/gpt-4.1":                        "GPT-4",
+	"openai/gpt-4o":                         "GPT-4o",
+	"gemini/gemini-2.5-flash-preview-05-20": "Gemini-2.5-Flash-Preview",
+	"gemini/gemini-2.0-flash":               "Gemini-2.0-Flash",
+	"gemini/gemini-2.5-pro-preview-05-06":   "Gemini-2.5-Pro-Preview",
+	"anthropic/claude-3-7-sonnet-20250219":  "Claude-3.7-Sonnet",
+}
+
+// Run 一次模型运行的附加信息，注册新模型时写入
+type Run struct {
+	RunDate       *time.Time
+	PromptVersion string
+}
+
+// ParseOutputKey 从 output_<provider>_<model> 字段名中拆出provider和模型版本
+func ParseOutputKey(key string) (provider, version string, ok bool) {
+	if !strings.HasPrefix(key, OutputPrefix) {
+		return "", "", false
+	}
+	provider, version, found := strings.Cut(strings.TrimPrefix(key, OutputPrefix), "_")
+	if !found || provider == "" || version == "" {
+		return "", "", false
+	}
+	return provider, version, true
+}
+
+// DisplayName 新注册模型的默认显示名称；同一模型的不同prompt版本用后缀区分
+func DisplayName(provider, version, promptVersion string) string {
+	name, ok := knownDisplayNames[provider+"/"+version]
+	if !ok {
+		name = version
+	}
+	if promptVersion != "" {
+		name = fmt.Sprintf("%s [%s]", name, promptVersion)
+	}
+	return name
+}
+
+const modelColumns = `id, provider, version, display_name, run_date, prompt_version, created_at`
+
+func scanModel(row db.Scanner) (models.Model, error) {
+	var model models.Model
+	var runDate sql.NullTime
+	err := row.Scan(&model.ID, &model.Provider, &model.Version, &model.DisplayName,
+		&runDate, &model.PromptVersion, &model.CreatedAt)
+	if runDate.Valid {
+		model.RunDate = &runDate.Time
+	}
+	return model, err
+}
+
+// Register 查找(provider, version, prompt_version)对应的模型，不存在时注册；
+// 已注册模型缺少运行日期时补上本次的日期。
+// 已有模型不会被加锁，多个导入事务可以并发注册同一模型。
+func Register(q db.Querier, provider, version string, run Run) (models.Model, error) {
+	model, err := scanModel(q.QueryRow(`
+		INSERT INTO models (provider, version, display_name, run_date, prompt_version)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, version, prompt_version) DO NOTHING
+		RETURNING `+modelColumns,
+		provider, version, DisplayName(provider, version, run.PromptVersion), run.RunDate, run.PromptVersion))
+	if err != sql.ErrNoRows {
+		return model, err
+	}
+
+	model, err = scanModel(q.QueryRow(`
+		SELECT `+modelColumns+` FROM models
+		WHERE provider = $1 AND version = $2 AND prompt_version = $3`,
+		provider, version, run.PromptVersion))
+	if err != nil || model.RunDate != nil || run.RunDate == nil {
+		return model, err
+	}
+
+	_, err = q.Exec(`UPDATE models SET run_date = $1 WHERE id = $2 AND run_date IS NULL`, run.RunDate, model.ID)
+	model.RunDate = run.RunDate
+	return model, err
+}
+
+// List 列出全部已注册模型
+func List(conn *sql.DB) ([]models.Model, error) {
+	rows, err := conn.Query(`SELECT ` + modelColumns + ` FROM models ORDER BY provider, version, prompt_version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Model{}
+	for rows.Next() {
+		model, err := scanModel(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, model)
+	}
+	return list, rows.Err()
+}
+
+// Update 修改模型显示名称和运行日期，并同步该模型已有标注的model_name
+func Update(tx *sql.Tx, id int, displayName string, runDate *time.Time) (models.Model, error) {
+	model, err := scanModel(tx.QueryRow(`
+		UPDATE models SET display_name = $1, run_date = $2 WHERE id = $3
+		RETURNING `+modelColumns, displayName, runDate, id))
+	if err != nil {
+		return model, err
+	}
+
+	_, err = tx.Exec(`UPDATE model_annotations SET model_name = $1 WHERE model_id = $2`, displayName, id)
+	return model, err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -38,30 +39,66 @@ type Review struct {
 	Text        string    `json:"text"`
 }
 
+// Model 模型注册表中的一次模型运行配置
+type Model struct {
+	ID            int        `json:"id"`
+	Provider      string     `json:"provider"`
+	Version       string     `json:"version"`
+	DisplayName   string     `json:"display_name"`
+	RunDate       *time.Time `json:"run_date"`
+	PromptVersion string     `json:"prompt_version"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
 // ModelAnnotation 模型人格标注表
 type ModelAnnotation struct {
-	ID          int    `json:"id"`
-	PhysicianID int    `json:"physician_id"`
-	ModelName   string `json:"model_name"`
-	Trait       string `json:"trait"`
-	Score       string `json:"score"`
-	Consistency string `json:"consistency"`
-	Sufficiency string `json:"sufficiency"`
-	Evidence    string `json:"evidence"`
+	ID                    int                `json:"id"`
+	PhysicianID           int                `json:"physician_id"`
+	ModelID               *int               `json:"model_id"`
+	ModelName             string             `json:"model_name"`
+	Trait                 string             `json:"trait"`
+	Score                 string             `json:"score"`
+	Consistency           string             `json:"consistency"`
+	Sufficiency           string             `json:"sufficiency"`
+	Evidence              string             `json:"evidence"`
+	Citations             []EvidenceCitation `json:"citations,omitempty"`
+	ScoreNormalized       *NormalizedScore   `json:"score_normalized,omitempty"`
+	ConsistencyNormalized *NormalizedScore   `json:"consistency_normalized,omitempty"`
+	SufficiencyNormalized *NormalizedScore   `json:"sufficiency_normalized,omitempty"`
+}
+
+// NormalizedScore 模型文本标签解析后的有序值（人类1-5评分尺度）
+type NormalizedScore struct {
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+	Value      float64 `json:"value"` // 范围中点
+	IsRange    bool    `json:"is_range"`
+	NoEvidence bool    `json:"no_evidence"`
 }
 
 // HumanAnnotation 人类标注结果
 type HumanAnnotation struct {
-	ID          int       `json:"id"`
-	PhysicianID int       `json:"physician_id"`
-	Evaluator   string    `json:"evaluator"`
-	TaskID      int       `json:"task_id"`
-	Trait       string    `json:"trait"`
-	Score       int       `json:"score"`
-	Consistency int       `json:"consistency"`
-	Sufficiency int       `json:"sufficiency"`
-	Evidence    string    `json:"evidence"`
-	Timestamp   time.Time `json:"timestamp"`
+	ID          int                `json:"id"`
+	PhysicianID int                `json:"physician_id"`
+	Evaluator   string             `json:"evaluator"`
+	TaskID      int                `json:"task_id"`
+	Trait       string             `json:"trait"`
+	Score       int                `json:"score"`
+	Consistency int                `json:"consistency"`
+	Sufficiency int                `json:"sufficiency"`
+	Evidence    string             `json:"evidence"`
+	Citations   []EvidenceCitation `json:"citations,omitempty"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// EvidenceCitation 标注引用的一段评论原文。偏移量按字符（Unicode码点）计算，区间为[start, end)
+type EvidenceCitation struct {
+	ReviewID    int    `json:"review_id"`
+	ReviewIndex int    `json:"review_index"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Quote       string `json:"quote"`
+	Stale       bool   `json:"stale,omitempty"` // 评论重新导入后原文已与quote不一致
 }
 
 // MachineAnnotationEvaluation 存储对机器标注的简单评价
@@ -78,6 +115,20 @@ type MachineAnnotationEvaluation struct {
 	Timestamp         time.Time `json:"timestamp"`
 }
 
+// HumanAnnotationRevision 人类标注的一次不可变提交记录
+type HumanAnnotationRevision struct {
+	HumanAnnotation
+	Revision int    `json:"revision"`
+	Stage    string `json:"stage"` // 提交时所处的工作流阶段，历史回填数据为空
+}
+
+// MachineEvaluationRevision 机器标注评价的一次不可变提交记录
+type MachineEvaluationRevision struct {
+	MachineAnnotationEvaluation
+	Revision int    `json:"revision"`
+	Stage    string `json:"stage"`
+}
+
 // TraitProgress 追踪用户在每个trait上的进度
 type TraitProgress struct {
 	ID                         int       `json:"id"`
@@ -88,6 +139,7 @@ type TraitProgress struct {
 	HumanAnnotationCompleted   bool      `json:"human_annotation_completed"`
 	MachineEvaluationCompleted bool      `json:"machine_evaluation_completed"`
 	ReviewCompleted            bool      `json:"review_completed"`
+	Stage                      string    `json:"stage"`
 	Timestamp                  time.Time `json:"timestamp"`
 }
 
@@ -95,11 +147,150 @@ type TraitProgress struct {
 type Task struct {
 	ID          int       `json:"id"`
 	PhysicianID int       `json:"physician_id"`
-	Status      string    `json:"status"` // pending, in_progress, completed
+	NPI         int64     `json:"npi,omitempty"`
+	Status      string    `json:"status"` // pending, in_progress, completed, cancelled, reopened
 	AssignedTo  string    `json:"assigned_to,omitempty"`
 	Timestamp   time.Time `json:"timestamp"`
 }
 
+// User 标注用户账号
+type User struct {
+	ID           int        `json:"id"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	Role         string     `json:"role"` // annotator, adjudicator, admin
+	CreatedAt    time.Time  `json:"created_at"`
+	QualifiedAt  *time.Time `json:"qualified_at"` // 通过资格测试的时间，未通过为空
+}
+
+// TaskStatus 任务状态枚举
+const (
+	TaskStatusPending    = "pending"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusCompleted  = "completed"
+	TaskStatusCancelled  = "cancelled"
+	TaskStatusReopened   = "reopened"
+)
+
+// TaskStatusChange 任务状态变更记录
+type TaskStatusChange struct {
+	ID          int       `json:"id"`
+	TaskID      int       `json:"task_id"`
+	PhysicianID int       `json:"physician_id"`
+	FromStatus  string    `json:"from_status"`
+	ToStatus    string    `json:"to_status"`
+	ChangedBy   string    `json:"changed_by"`
+	Reason      string    `json:"reason"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// AdjudicationTask 人类标注分歧的仲裁任务
+type AdjudicationTask struct {
+	ID          int        `json:"id"`
+	PhysicianID int        `json:"physician_id"`
+	NPI         int64      `json:"npi,omitempty"`
+	Trait       string     `json:"trait"`
+	Status      string     `json:"status"` // open, resolved, cancelled
+	AssignedTo  string     `json:"assigned_to,omitempty"`
+	Spread      float64    `json:"spread"`
+	Threshold   float64    `json:"threshold"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// AdjudicatedLabel 仲裁给出的最终标签
+type AdjudicatedLabel struct {
+	ID             int       `json:"id"`
+	AdjudicationID int       `json:"adjudication_id"`
+	PhysicianID    int       `json:"physician_id"`
+	Trait          string    `json:"trait"`
+	Score          int       `json:"score"`
+	Consistency    int       `json:"consistency"`
+	Sufficiency    int       `json:"sufficiency"`
+	Rationale      string    `json:"rationale"`
+	Adjudicator    string    `json:"adjudicator"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// AdjudicationStatus 仲裁任务状态枚举
+const (
+	AdjudicationOpen      = "open"
+	AdjudicationResolved  = "resolved"
+	AdjudicationCancelled = "cancelled"
+)
+
+// GoldLabel 管理员设定的医生/trait参考标签
+type GoldLabel struct {
+	ID             int       `json:"id"`
+	PhysicianID    int       `json:"physician_id"`
+	NPI            int64     `json:"npi"`
+	Trait          string    `json:"trait"`
+	Score          int       `json:"score"`
+	Consistency    int       `json:"consistency"`
+	Sufficiency    int       `json:"sufficiency"`
+	Qualification  bool      `json:"qualification"`   // 是否用于资格测试
+	AttentionCheck bool      `json:"attention_check"` // 是否用于注意力检查
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// QualificationAttempt 一次资格测试
+type QualificationAttempt struct {
+	ID          int        `json:"id"`
+	Evaluator   string     `json:"evaluator"`
+	Items       int        `json:"items"`
+	Answered    int        `json:"answered"`
+	WithinOne   *float64   `json:"within_one"`
+	Kappa       *float64   `json:"kappa"`
+	Passed      *bool      `json:"passed"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// AttentionCheckAlert 评分者近期注意力检查准确率低于阈值时的告警
+type AttentionCheckAlert struct {
+	ID             int        `json:"id"`
+	Evaluator      string     `json:"evaluator"`
+	Accuracy       float64    `json:"accuracy"`
+	Results        int        `json:"results"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// ConsensusRun 一次共识计算，即共识标签的一个版本
+type ConsensusRun struct {
+	ID         int             `json:"id"`
+	Method     string          `json:"method"` // majority, median, dawid_skene
+	Parameters json.RawMessage `json:"parameters"`
+	Units      int             `json:"units"`
+	CreatedBy  string          `json:"created_by"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ConsensusLabel 某版本中某医生某trait某维度的共识标签
+type ConsensusLabel struct {
+	RunID       int     `json:"run_id"`
+	PhysicianID int     `json:"physician_id"`
+	NPI         int64   `json:"npi,omitempty"`
+	Trait       string  `json:"trait"`
+	Dimension   string  `json:"dimension"`
+	Label       float64 `json:"label"`
+	Confidence  float64 `json:"confidence"`
+	Annotators  int     `json:"annotators"`
+}
+
+// AnnotatorReliability 某版本中评分者在某trait某维度上的可靠性估计
+type AnnotatorReliability struct {
+	RunID     int                           `json:"run_id"`
+	Evaluator string                        `json:"evaluator"`
+	Trait     string                        `json:"trait"`
+	Dimension string                        `json:"dimension"`
+	Items     int                           `json:"items"`
+	Accuracy  *float64                      `json:"accuracy"`
+	Confusion map[string]map[string]float64 `json:"confusion,omitempty"` // 真实标签 -> 评分 -> 概率
+}
+
 // TraitWorkflowStage 工作流阶段枚举
 const (
 	StageHumanAnnotation   = "human_annotation"
@@ -108,6 +299,13 @@ const (
 	StageCompleted         = "completed"
 )
 
+// Role 用户角色枚举
+const (
+	RoleAnnotator   = "annotator"
+	RoleAdjudicator = "adjudicator"
+	RoleAdmin       = "admin"
+)
+
 // Rating 评价枚举
 const (
 	RatingThumbUp   = "thumb_up"
@@ -123,3 +321,22 @@ const (
 	TraitAgreeableness     = "agreeableness"
 	TraitNeuroticism       = "neuroticism"
 )
+
+// AllTraits 全部人格特质
+var AllTraits = []string{
+	TraitOpenness,
+	TraitConscientiousness,
+	TraitExtraversion,
+	TraitAgreeableness,
+	TraitNeuroticism,
+}
+
+// IsValidTrait 检查trait名称是否合法
+func IsValidTrait(trait string) bool {
+	for _, t := range AllTraits {
+		if t == trait {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package qualification
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/phyreview_annotator/analytics"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+	"github.com/phyreview_annotator/stats"
+)
+
+var (
+	// ErrNoItems 没有用于资格测试的参考标签
+	ErrNoItems = errors.New("no qualification items")
+	// ErrItemNotInAttempt 题目不属于当前测试
+	ErrItemNotInAttempt = errors.New("item is not part of the current attempt")
+)
+
+// Config 通过资格测试的阈值
+type Config struct {
+	MinWithinOne float64 `json:"min_within_one"` // 与参考标签相差不超过1分的最低比例
+	MinKappa     float64 `json:"min_kappa"`      // 与参考标签的最低Cohen's kappa
+}
+
+// LoadConfig 从环境变量读取阈值：QUALIFICATION_MIN_WITHIN_ONE（默认0.8）、QUALIFICATION_MIN_KAPPA（默认0.4）
+func LoadConfig() Config {
+	cfg := Config{MinWithinOne: 0.8, MinKappa: 0.4}
+	if value := os.Getenv("QUALIFICATION_MIN_WITHIN_ONE"); value != "" {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 && v <= 1 {
+			cfg.MinWithinOne = v
+		} else {
+			log.Printf("Warning: invalid QUALIFICATION_MIN_WITHIN_ONE %q, using %g", value, cfg.MinWithinOne)
+		}
+	}
+	if value := os.Getenv("QUALIFICATION_MIN_KAPPA"); value != "" {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v <= 1 {
+			cfg.MinKappa = v
+		} else {
+			log.Printf("Warning: invalid QUALIFICATION_MIN_KAPPA %q, using %g", value, cfg.MinKappa)
+		}
+	}
+	return cfg
+}
+
+// Required 用户是否需要先通过资格测试：非admin、尚未通过，且存在资格测试题目
+func Required(q db.Querier, username string) (bool, error) {
+	var required bool
+	err := q.QueryRow(`
+		SELECT u.role <> $2 AND u.qualified_at IS NULL
+			AND EXISTS (SELECT 1 FROM gold_labels WHERE qualification)
+		FROM users u WHERE u.username = $1
+	`, username, models.RoleAdmin).Scan(&required)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return required, err
+}
+
+// Label 三个维度的评分
+type Label struct {
+	Score       int `json:"score"`
+	Consistency int `json:"consistency"`
+	Sufficiency int `json:"sufficiency"`
+}
+
+// Value 返回指定维度的评分
+func (l Label) Value(dimension string) int {
+	switch dimension {
+	case analytics.DimensionConsistency:
+		return l.Consistency
+	case analytics.DimensionSufficiency:
+		return l.Sufficiency
+	default:
+		return l.Score
+	}
+}
+
+// Answer 一道题的作答和参考标签
+type Answer struct {
+	Given     Label
+	Reference Label
+}
+
+// Result 测试得分。每道题的三个维度各算一次比较
+type Result struct {
+	Items     int      `json:"items"`
+	WithinOne *float64 `json:"within_one"`
+	Kappa     *float64 `json:"kappa"`
+	Passed    bool     `json:"passed"`
+}
+
+// Score 计算与参考标签相差不超过1分的比例，以及全部维度合并后的Cohen's kappa
+func Score(answers []Answer) Result {
+	result := Result{Items: len(answers)}
+	var given, reference []int
+	close := 0
+	for _, answer := range answers {
+		for _, dimension := range analytics.Dimensions {
+			g, r := answer.Given.Value(dimension), answer.Reference.Value(dimension)
+			given = append(given, g)
+			reference = append(reference, r)
+			if math.Abs(float64(g-r)) <= 1 {
+				close++
+			}
+		}
+	}
+	if len(given) > 0 {
+		result.WithinOne = stats.Float(float64(close)/float64(len(given)), true)
+	}
+	result.Kappa = stats.Float(stats.CohenKappa(given, reference))
+	return result
+}
+
+// Meets 是否达到阈值。参考标签只用了同一个评分时kappa无定义，只看within-one
+func (r Result) Meets(cfg Config) bool {
+	if r.WithinOne == nil || *r.WithinOne < cfg.MinWithinOne {
+		return false
+	}
+	return r.Kappa == nil || *r.Kappa >= cfg.MinKappa
+}
+
+const attemptColumns = `a.id, a.evaluator, a.items,
+	(SELECT COUNT(*) FROM qualification_answers x WHERE x.attempt_id = a.id AND x.answered_at IS NOT NULL),
+	a.within_one, a.kappa, a.passed, a.started_at, a.completed_at`
+
+func scanAttempt(row db.Scanner) (models.QualificationAttempt, error) {
+	var attempt models.QualificationAttempt
+	var withinOne, kappa sql.NullFloat64
+	var passed sql.NullBool
+	var completedAt sql.NullTime
+	err := row.Scan(&attempt.ID, &attempt.Evaluator, &attempt.Items, &attempt.Answered,
+		&withinOne, &kappa, &passed, &attempt.StartedAt, &completedAt)
+	if withinOne.Valid {
+		attempt.WithinOne = &withinOne.Float64
+	}
+	if kappa.Valid {
+		attempt.Kappa = &kappa.Float64
+	}
+	if passed.Valid {
+		attempt.Passed = &passed.Bool
+	}
+	if completedAt.Valid {
+		attempt.CompletedAt = &completedAt.Time
+	}
+	return attempt, err
+}
+
+// OpenAttempt 用户未完成的测试，没有时返回sql.ErrNoRows
+func OpenAttempt(q db.Querier, username string) (models.QualificationAttempt, error) {
+	return scanAttempt(q.QueryRow(`
+		SELECT `+attemptColumns+` FROM qualification_attempts a
+		WHERE a.evaluator = $1 AND a.completed_at IS NULL
+	`, username))
+}
+
+// ListAttempts 列出测试记录，最新的在前；username为空时列出全部
+func ListAttempts(q db.Querier, username string) ([]models.QualificationAttempt, error) {
+	rows, err := q.Query(`
+		SELECT `+attemptColumns+` FROM qualification_attempts a
+		WHERE ($1 = '' OR a.evaluator = $1)
+		ORDER BY a.started_at DESC, a.id DESC
+	`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.QualificationAttempt{}
+	for rows.Next() {
+		attempt, err := scanAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// StartAttempt 开始新测试：当前全部资格测试题目以随机顺序加入
+func StartAttempt(q db.Querier, username string) (models.QualificationAttempt, error) {
+	var id int
+	err := q.QueryRow(`
+		INSERT INTO qualification_attempts (evaluator, items, started_at)
+		SELECT $1, COUNT(*), $2 FROM gold_labels WHERE qualification
+		RETURNING id
+	`, username, time.Now()).Scan(&id)
+	if err != nil {
+		return models.QualificationAttempt{}, err
+	}
+
+	result, err := q.Exec(`
+		INSERT INTO qualification_answers (attempt_id, gold_label_id, position)
+		SELECT $1, id, ROW_NUMBER() OVER (ORDER BY random()) FROM gold_labels WHERE qualification
+	`, id)
+	if err != nil {
+		return models.QualificationAttempt{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return models.QualificationAttempt{}, ErrNoItems
+	}
+
+	return scanAttempt(q.QueryRow(`SELECT `+attemptColumns+` FROM qualification_attempts a WHERE a.id = $1`, id))
+}
+
+// Item 测试中的一道题，不包含参考标签
+type Item struct {
+	GoldLabelID int    `json:"item_id"`
+	Position    int    `json:"position"`
+	PhysicianID int    `json:"physician_id"`
+	NPI         int64  `json:"npi"`
+	Trait       string `json:"trait"`
+}
+
+// NextItem 测试中下一道未作答的题，全部作答后返回sql.ErrNoRows
+func NextItem(q db.Querier, attemptID int) (Item, error) {
+	var item Item
+	err := q.QueryRow(`
+		SELECT x.gold_label_id, x.position, g.physician_id, p.npi, g.trait
+		FROM qualification_answers x
+		JOIN gold_labels g ON g.id = x.gold_label_id
+		JOIN physicians p ON p.id = g.physician_id
+		WHERE x.attempt_id = $1 AND x.answered_at IS NULL
+		ORDER BY x.position
+		LIMIT 1
+	`, attemptID).Scan(&item.GoldLabelID, &item.Position, &item.PhysicianID, &item.NPI, &item.Trait)
+	return item, err
+}
+
+// SubmitAnswer 保存作答，可以在测试完成前修改。题目不属于该测试时返回ErrItemNotInAttempt
+func SubmitAnswer(q db.Querier, attemptID, goldLabelID int, label Label) error {
+	result, err := q.Exec(`
+		UPDATE qualification_answers
+		SET score = $1, consistency = $2, sufficiency = $3, answered_at = $4
+		WHERE attempt_id = $5 AND gold_label_id = $6
+	`, label.Score, label.Consistency, label.Sufficiency, time.Now(), attemptID, goldLabelID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrItemNotInAttempt
+	}
+	return nil
+}
+
+// Finish 全部作答后评分并结束测试，通过时记录用户的通过时间。
+// 还有未作答的题时返回nil
+func Finish(q db.Querier, attempt models.QualificationAttempt, cfg Config) (*Result, error) {
+	rows, err := q.Query(`
+		SELECT x.score, x.consistency, x.sufficiency, g.score, g.consistency, g.sufficiency, x.answered_at IS NOT NULL
+		FROM qualification_answers x JOIN gold_labels g ON g.id = x.gold_label_id
+		WHERE x.attempt_id = $1
+	`, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var answers []Answer
+	for rows.Next() {
+		var given [3]sql.NullInt64
+		var answer Answer
+		var answered bool
+		err := rows.Scan(&given[0], &given[1], &given[2],
+			&answer.Reference.Score, &answer.Reference.Consistency, &answer.Reference.Sufficiency, &answered)
+		if err != nil {
+			return nil, err
+		}
+		if !answered {
+			return nil, nil
+		}
+		answer.Given = Label{int(given[0].Int64), int(given[1].Int64), int(given[2].Int64)}
+		answers = append(answers, answer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := Score(answers)
+	result.Passed = result.Meets(cfg)
+	now := time.Now()
+	_, err = q.Exec(`
+		UPDATE qualification_attempts
+		SET items = $1, within_one = $2, kappa = $3, passed = $4, completed_at = $5
+		WHERE id = $6
+	`, result.Items, result.WithinOne, result.Kappa, result.Passed, now, attempt.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Passed {
+		_, err = q.Exec(`UPDATE users SET qualified_at = $1 WHERE username = $2 AND qualified_at IS NULL`, now, attempt.Evaluator)
+	}
+	return &result, err
+}
@@ -0,0 +1,108 @@
+package qualification
+
+import (
+	"math"
+	"testing"
+
+	"github.com/phyreview_annotator/stats"
+)
+
+func label(score, consistency, sufficiency int) Label {
+	return Label{Score: score, Consistency: consistency, Sufficiency: sufficiency}
+}
+
+func assertFloat(t *testing.T, name string, got *float64, want *float64) {
+	t.Helper()
+	switch {
+	case want == nil && got != nil:
+		t.Errorf("%s = %v, want nil", name, *got)
+	case want != nil && (got == nil || math.Abs(*got-*want) > 1e-9):
+		t.Errorf("%s = %v, want %v", name, got, *want)
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		answers   []Answer
+		withinOne *float64
+		kappa     *float64
+	}{
+		{
+			// 相差1分算接近，相差2分不算
+			name:      "within one at the boundary",
+			answers:   []Answer{{Given: label(3, 3, 3), Reference: label(4, 2, 5)}},
+			withinOne: stats.Float(2.0/3, true),
+			kappa:     stats.Float(0, true),
+		},
+		{
+			name: "perfect agreement",
+			answers: []Answer{
+				{Given: label(1, 2, 3), Reference: label(1, 2, 3)},
+				{Given: label(4, 5, 5), Reference: label(4, 5, 5)},
+			},
+			withinOne: stats.Float(1, true),
+			kappa:     stats.Float(1, true),
+		},
+		{
+			// 参考标签和作答都只用了同一个评分：期望一致率为1，kappa无定义
+			name:      "all one value, undefined kappa",
+			answers:   []Answer{{Given: label(3, 3, 3), Reference: label(3, 3, 3)}, {Given: label(3, 3, 3), Reference: label(3, 3, 3)}},
+			withinOne: stats.Float(1, true),
+		},
+		{
+			// 参考标签只有一个评分但作答不同：po = 4/6，pe = 4/6，kappa为0
+			name:      "reference all one value",
+			answers:   []Answer{{Given: label(3, 3, 4), Reference: label(3, 3, 3)}, {Given: label(3, 3, 4), Reference: label(3, 3, 3)}},
+			withinOne: stats.Float(1, true),
+			kappa:     stats.Float(0, true),
+		},
+		{name: "no answers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Score(tt.answers)
+			if result.Items != len(tt.answers) {
+				t.Errorf("items = %d, want %d", result.Items, len(tt.answers))
+			}
+			assertFloat(t, "within_one", result.WithinOne, tt.withinOne)
+			assertFloat(t, "kappa", result.Kappa, tt.kappa)
+		})
+	}
+}
+
+func TestMeets(t *testing.T) {
+	cfg := Config{MinWithinOne: 0.8, MinKappa: 0.4}
+	tests := []struct {
+		name   string
+		result Result
+		want   bool
+	}{
+		{"thresholds exactly met", Result{WithinOne: stats.Float(0.8, true), Kappa: stats.Float(0.4, true)}, true},
+		{"within one just below", Result{WithinOne: stats.Float(0.79, true), Kappa: stats.Float(0.9, true)}, false},
+		{"kappa just below", Result{WithinOne: stats.Float(1, true), Kappa: stats.Float(0.39, true)}, false},
+		{"undefined kappa only checks within one", Result{WithinOne: stats.Float(0.8, true)}, true},
+		{"undefined kappa and within one below", Result{WithinOne: stats.Float(0.5, true)}, false},
+		{"no answers", Result{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Meets(cfg); got != tt.want {
+				t.Errorf("Meets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 由Score算出的比例恰好等于阈值：15次比较中12次相差不超过1分
+	var answers []Answer
+	for i := 0; i < 4; i++ {
+		answers = append(answers, Answer{Given: label(i+1, i+1, i+1), Reference: label(i+1, i+1, i+1)})
+	}
+	answers = append(answers, Answer{Given: label(1, 1, 1), Reference: label(5, 5, 5)})
+	result := Score(answers)
+	if *result.WithinOne != 0.8 || !result.Meets(Config{MinWithinOne: 0.8}) {
+		t.Errorf("within_one = %v, want exactly 0.8 to pass", *result.WithinOne)
+	}
+}
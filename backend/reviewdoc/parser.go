@@ -0,0 +1,114 @@
+package reviewdoc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout 评论meta中的时间格式，如 2009-04-03 14:53:21
+const DateLayout = "2006-01-02 15:04:05"
+
+// Entry 从review_doc中解析出的单条评论
+type Entry struct {
+	Index  int
+	Date   time.Time
+	Source string
+	Text   string
+}
+
+// Malformed 无法解析的评论片段
+type Malformed struct {
+	Position int    // 在文档中的顺序（从0开始）
+	Snippet  string // 截断后的原始内容，便于定位
+	Reason   string
+}
+
+func (m Malformed) String() string {
+	return fmt.Sprintf("review #%d: %s (%q)", m.Position, m.Reason, m.Snippet)
+}
+
+var (
+	// reviewBlock 匹配单条 <review>...</review>
+	reviewBlock = regexp.MustCompile(`(?s)<review>(.*?)</review>`)
+	// metaBlock 拆分meta头与正文
+	metaBlock = regexp.MustCompile(`(?s)^\s*<meta>(.*?)</meta>(.*)$`)
+	// metaFields 匹配 "#0 - 2009-04-03 14:53:21 - Vitals"
+	metaFields = regexp.MustCompile(`^#(\d+)\s+-\s+(\d{4}-\d{2}-\d{2}(?: \d{2}:\d{2}:\d{2})?)\s+-\s+(.+)$`)
+)
+
+// Parse 解析review_doc，返回合法的评论和无法解析的片段。
+// 每条评论必须带有 <meta>#序号 - 时间 - 来源</meta> 头，序号不能重复，正文不能为空。
+func Parse(doc string) ([]Entry, []Malformed) {
+	var entries []Entry
+	var malformed []Malformed
+	seen := map[int]bool{}
+
+	for position, block := range reviewBlock.FindAllStringSubmatch(doc, -1) {
+		entry, err := parseBlock(block[1])
+		if err == nil && seen[entry.Index] {
+			err = fmt.Errorf("duplicate index %d", entry.Index)
+		}
+		if err != nil {
+			malformed = append(malformed, Malformed{
+				Position: position,
+				Snippet:  snippet(block[1]),
+				Reason:   err.Error(),
+			})
+			continue
+		}
+		seen[entry.Index] = true
+		entries = append(entries, entry)
+	}
+
+	return entries, malformed
+}
+
+func parseBlock(block string) (Entry, error) {
+	parts := metaBlock.FindStringSubmatch(block)
+	if parts == nil {
+		return Entry{}, fmt.Errorf("missing <meta> header")
+	}
+
+	meta := strings.Join(strings.Fields(parts[1]), " ")
+	fields := metaFields.FindStringSubmatch(meta)
+	if fields == nil {
+		return Entry{}, fmt.Errorf("malformed meta %q", meta)
+	}
+
+	index, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid index %q", fields[1])
+	}
+
+	date, err := ParseDate(fields[2])
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid date %q", fields[2])
+	}
+
+	source := strings.TrimSpace(fields[3])
+	text := strings.TrimSpace(parts[2])
+	if text == "" {
+		return Entry{}, fmt.Errorf("empty review text")
+	}
+
+	return Entry{Index: index, Date: date, Source: source, Text: text}, nil
+}
+
+// ParseDate 解析评论时间，支持带时间和仅有日期两种写法
+func ParseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(DateLayout, value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func snippet(block string) string {
+	runes := []rune(strings.Join(strings.Fields(block), " "))
+	if len(runes) > 80 {
+		return string(runes[:80]) + "..."
+	}
+	return string(runes)
+}
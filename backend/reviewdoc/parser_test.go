@@ -0,0 +1,97 @@
+package reviewdoc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWellFormed(t *testing.T) {
+	doc := `<review><meta>#0 - 2009-04-03 14:53:21 - Vitals</meta> Great doctor, very patient. </review>
+<review>
+  <meta>
+    #1 -   2010-01-02 - Healthgrades
+  </meta>
+  Long wait,
+  but worth it.
+</review>
+<review><meta>#7 - 2011-06-30 08:00:00 - Yelp - Mobile</meta>简短评论 👍</review>`
+
+	entries, malformed := Parse(doc)
+	if len(malformed) != 0 {
+		t.Fatalf("malformed = %v, want none", malformed)
+	}
+
+	want := []Entry{
+		{Index: 0, Date: time.Date(2009, 4, 3, 14, 53, 21, 0, time.UTC), Source: "Vitals", Text: "Great doctor, very patient."},
+		{Index: 1, Date: time.Date(2010, 1, 2, 0, 0, 0, 0, time.UTC), Source: "Healthgrades", Text: "Long wait,\n  but worth it."},
+		// 来源中的" - "保留在来源里
+		{Index: 7, Date: time.Date(2011, 6, 30, 8, 0, 0, 0, time.UTC), Source: "Yelp - Mobile", Text: "简短评论 👍"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		block  string
+		reason string
+	}{
+		{"missing meta", `<review>No header here</review>`, "missing <meta> header"},
+		{"index not a number", `<review><meta>#x - 2009-04-03 - Vitals</meta>text</review>`, "malformed meta"},
+		{"index without hash", `<review><meta>0 - 2009-04-03 - Vitals</meta>text</review>`, "malformed meta"},
+		{"index out of range", `<review><meta>#99999999999999999999 - 2009-04-03 - Vitals</meta>text</review>`, "invalid index"},
+		{"date in another format", `<review><meta>#0 - 04/03/2009 - Vitals</meta>text</review>`, "malformed meta"},
+		{"impossible date", `<review><meta>#0 - 2009-13-45 - Vitals</meta>text</review>`, "invalid date"},
+		{"impossible time", `<review><meta>#0 - 2009-04-03 25:61:00 - Vitals</meta>text</review>`, "invalid date"},
+		{"missing source", `<review><meta>#0 - 2009-04-03 -</meta>text</review>`, "malformed meta"},
+		{"missing source and separator", `<review><meta>#0 - 2009-04-03</meta>text</review>`, "malformed meta"},
+		{"empty text", `<review><meta>#0 - 2009-04-03 - Vitals</meta>   </review>`, "empty review text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 前面放一条合法评论，确认只有出错的片段被跳过
+			doc := `<review><meta>#5 - 2009-01-01 - Vitals</meta>ok</review>` + tt.block
+			entries, malformed := Parse(doc)
+			if len(entries) != 1 || entries[0].Index != 5 {
+				t.Errorf("entries = %+v, want only #5", entries)
+			}
+			if len(malformed) != 1 {
+				t.Fatalf("malformed = %v, want one", malformed)
+			}
+			if malformed[0].Position != 1 || !strings.Contains(malformed[0].Reason, tt.reason) {
+				t.Errorf("malformed = %+v, want position 1 and reason %q", malformed[0], tt.reason)
+			}
+		})
+	}
+}
+
+func TestParseDuplicateIndex(t *testing.T) {
+	doc := `<review><meta>#0 - 2009-04-03 - Vitals</meta>first</review>` +
+		`<review><meta>#0 - 2010-04-03 - Yelp</meta>second</review>`
+	entries, malformed := Parse(doc)
+	if len(entries) != 1 || entries[0].Text != "first" {
+		t.Errorf("entries = %+v, want only the first review", entries)
+	}
+	if len(malformed) != 1 || malformed[0].Reason != "duplicate index 0" {
+		t.Errorf("malformed = %v, want a duplicate index", malformed)
+	}
+}
+
+func TestMalformedSnippetIsTruncated(t *testing.T) {
+	_, malformed := Parse("<review>" + strings.Repeat("é", 100) + "</review>")
+	if len(malformed) != 1 {
+		t.Fatalf("malformed = %v, want one", malformed)
+	}
+	if want := strings.Repeat("é", 80) + "..."; malformed[0].Snippet != want {
+		t.Errorf("snippet = %q, want 80 runes and an ellipsis", malformed[0].Snippet)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/phyreview_annotator/controllers"
+	"github.com/phyreview_annotator/middleware"
+	"github.com/phyreview_annotator/models"
 )
 
 // SetupRouter 配置API路由
@@ -26,9 +28,24 @@ func SetupRouter() *gin.Engine {
 		})
 	})
 
-	// API路由组
+	// 登录路由（无需认证）
+	r.POST("/api/auth/login", controllers.Login)
+
+	// API路由组，需要登录
 	api := r.Group("/api")
+	api.Use(middleware.RequireAuth())
 	{
+		// 获取当前登录用户
+		api.GET("/auth/me", controllers.GetCurrentUser)
+
+		// 领取下一个待处理任务
+		api.GET("/me/next-task", controllers.GetNextTask)
+
+		// 资格测试
+		api.GET("/qualification", controllers.GetQualificationStatus)
+		api.GET("/qualification/next", controllers.GetNextQualificationItem)
+		api.POST("/qualification/items/:id/answer", controllers.SubmitQualificationAnswer)
+
 		// 获取医生信息
 		api.GET("/physician/:npi", controllers.GetPhysicianByNPI)
 
@@ -58,5 +75,61 @@ func SetupRouter() *gin.Engine {
 		api.POST("/physician/:npi/task/:taskID/trait/:trait/complete", controllers.CompleteTraitReview)
 	}
 
+	// 管理员路由组
+	admin := api.Group("/admin")
+	admin.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		// 用户管理
+		admin.GET("/users", controllers.ListUsers)
+		admin.POST("/users", controllers.CreateUser)
+		admin.PUT("/users/:username/role", controllers.UpdateUserRole)
+		admin.PUT("/users/:username/qualification", controllers.UpdateUserQualification)
+
+		// 任务管理
+		admin.GET("/tasks", controllers.ListTasks)
+		admin.POST("/tasks", controllers.CreateTasks)
+		admin.PUT("/tasks/assign", controllers.AssignTasks)
+		admin.PUT("/physician/:npi/task/:taskID/status", controllers.UpdateTaskStatus)
+		admin.GET("/physician/:npi/task/:taskID/status-history", controllers.GetTaskStatusHistory)
+
+		// 分析统计
+		admin.GET("/analytics/agreement", controllers.GetInterAnnotatorAgreement)
+		admin.GET("/analytics/model-agreement", controllers.GetHumanModelAgreement)
+		admin.GET("/score-mappings", controllers.GetScoreMappings)
+		admin.PUT("/score-mappings", controllers.UpdateScoreMappings)
+
+		// 参考标签和资格测试
+		admin.GET("/gold-labels", controllers.ListGoldLabels)
+		admin.PUT("/gold-labels", controllers.UpsertGoldLabels)
+		admin.DELETE("/gold-labels/:id", controllers.DeleteGoldLabel)
+		admin.GET("/qualification/attempts", controllers.ListQualificationAttempts)
+
+		// 注意力检查
+		admin.GET("/attention-checks", controllers.GetAttentionChecks)
+		admin.GET("/attention-checks/alerts", controllers.ListAttentionCheckAlerts)
+		admin.POST("/attention-checks/alerts/:id/acknowledge", controllers.AcknowledgeAttentionCheckAlert)
+
+		// 共识标签
+		admin.GET("/consensus/runs", controllers.ListConsensusRuns)
+		admin.POST("/consensus/runs", controllers.CreateConsensusRun)
+		admin.GET("/consensus/runs/:id", controllers.GetConsensusRun)
+
+		// 模型注册表
+		admin.GET("/models", controllers.ListModels)
+		admin.PUT("/models/:id", controllers.UpdateModel)
+
+		// 标注分歧仲裁
+		admin.POST("/adjudications/detect", controllers.DetectAdjudications)
+	}
+
+	// 仲裁路由组，adjudicator和admin可访问
+	adjudications := api.Group("/adjudications")
+	adjudications.Use(middleware.RequireRole(models.RoleAdjudicator))
+	{
+		adjudications.GET("", controllers.ListAdjudications)
+		adjudications.GET("/:id", controllers.GetAdjudication)
+		adjudications.POST("/:id/resolve", controllers.ResolveAdjudication)
+	}
+
 	return r
 }
@@ -0,0 +1,97 @@
+package scores
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/phyreview_annotator/models"
+)
+
+// levels 单一等级词汇到人类1-5评分尺度的映射
+var levels = map[string]float64{
+	"very low":  1,
+	"low":       2,
+	"moderate":  3,
+	"medium":    3,
+	"high":      4,
+	"very high": 5,
+}
+
+// noEvidenceLabels 表示无法判断的标签
+var noEvidenceLabels = map[string]bool{
+	"no evidence":           true,
+	"insufficient evidence": true,
+	"not enough evidence":   true,
+	"n/a":                   true,
+	"na":                    true,
+	"none":                  true,
+}
+
+// rangeSeparator 范围标签的分隔方式："Low to Moderate"、"Low-Moderate"、"Low/Moderate"
+var rangeSeparator = regexp.MustCompile(`\s+to\s+|\s*[-/–]\s*`)
+
+// Parse 将模型输出的文本标签解析为结构化有序值。
+// 支持单一等级（"High"）、范围（"Moderate to High"）和无证据（"No Evidence"）。
+func Parse(raw string) (models.NormalizedScore, error) {
+	label := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if label == "" {
+		return models.NormalizedScore{}, fmt.Errorf("empty score label")
+	}
+
+	if noEvidenceLabels[label] {
+		return models.NormalizedScore{NoEvidence: true}, nil
+	}
+
+	if value, ok := levels[label]; ok {
+		return models.NormalizedScore{Low: value, High: value, Value: value}, nil
+	}
+
+	parts := rangeSeparator.Split(label, -1)
+	if len(parts) == 2 {
+		low, okLow := levels[parts[0]]
+		high, okHigh := levels[parts[1]]
+		if okLow && okHigh {
+			if low > high {
+				low, high = high, low
+			}
+			return models.NormalizedScore{
+				Low:     low,
+				High:    high,
+				Value:   (low + high) / 2,
+				IsRange: low != high,
+			}, nil
+		}
+	}
+
+	return models.NormalizedScore{}, fmt.Errorf("unrecognized score label %q", raw)
+}
+
+// Columns 将解析结果转换为数据库列值（low、high、no_evidence），解析失败时均为NULL
+func Columns(score models.NormalizedScore, err error) (low, high, noEvidence interface{}) {
+	if err != nil {
+		return nil, nil, nil
+	}
+	if score.NoEvidence {
+		return nil, nil, true
+	}
+	return score.Low, score.High, false
+}
+
+// FromColumns 由数据库列值还原解析结果，未解析时返回nil
+func FromColumns(low, high sql.NullFloat64, noEvidence sql.NullBool) *models.NormalizedScore {
+	if !noEvidence.Valid {
+		return nil
+	}
+	if noEvidence.Bool {
+		return &models.NormalizedScore{NoEvidence: true}
+	}
+	score := models.NormalizedScore{
+		Low:     low.Float64,
+		High:    high.Float64,
+		Value:   (low.Float64 + high.Float64) / 2,
+		IsRange: low.Float64 != high.Float64,
+	}
+	return &score
+}
@@ -0,0 +1,85 @@
+package scores
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/phyreview_annotator/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    models.NormalizedScore
+		wantErr bool
+	}{
+		// 单一等级，忽略大小写和多余空白
+		{raw: "High", want: models.NormalizedScore{Low: 4, High: 4, Value: 4}},
+		{raw: "  very   HIGH ", want: models.NormalizedScore{Low: 5, High: 5, Value: 5}},
+		{raw: "Very Low", want: models.NormalizedScore{Low: 1, High: 1, Value: 1}},
+		{raw: "Medium", want: models.NormalizedScore{Low: 3, High: 3, Value: 3}},
+
+		// 范围：to、连字符、斜杠和en dash分隔，颠倒的范围按从低到高保存
+		{raw: "Low to Moderate", want: models.NormalizedScore{Low: 2, High: 3, Value: 2.5, IsRange: true}},
+		{raw: "Moderate to Low", want: models.NormalizedScore{Low: 2, High: 3, Value: 2.5, IsRange: true}},
+		{raw: "Low-Moderate", want: models.NormalizedScore{Low: 2, High: 3, Value: 2.5, IsRange: true}},
+		{raw: "Low / Moderate", want: models.NormalizedScore{Low: 2, High: 3, Value: 2.5, IsRange: true}},
+		{raw: "Moderate–High", want: models.NormalizedScore{Low: 3, High: 4, Value: 3.5, IsRange: true}},
+		{raw: "Very High to Low", want: models.NormalizedScore{Low: 2, High: 5, Value: 3.5, IsRange: true}},
+		{raw: "High to High", want: models.NormalizedScore{Low: 4, High: 4, Value: 4}},
+
+		// 无证据
+		{raw: "No Evidence", want: models.NormalizedScore{NoEvidence: true}},
+		{raw: "insufficient evidence", want: models.NormalizedScore{NoEvidence: true}},
+		{raw: "N/A", want: models.NormalizedScore{NoEvidence: true}},
+		{raw: "None", want: models.NormalizedScore{NoEvidence: true}},
+
+		// 无法识别
+		{raw: "", wantErr: true},
+		{raw: "   ", wantErr: true},
+		{raw: "Excellent", wantErr: true},
+		{raw: "4", wantErr: true},
+		{raw: "Low to Excellent", wantErr: true},
+		{raw: "Low to Moderate to High", wantErr: true},
+		{raw: "Low-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnsRoundTrip(t *testing.T) {
+	for _, raw := range []string{"High", "Low to Moderate", "No Evidence"} {
+		score, err := Parse(raw)
+		low, high, noEvidence := Columns(score, err)
+
+		var l, h sql.NullFloat64
+		var n sql.NullBool
+		if low != nil {
+			l = sql.NullFloat64{Float64: low.(float64), Valid: true}
+			h = sql.NullFloat64{Float64: high.(float64), Valid: true}
+		}
+		n = sql.NullBool{Bool: noEvidence.(bool), Valid: true}
+		if got := FromColumns(l, h, n); got == nil || *got != score {
+			t.Errorf("%q: FromColumns = %+v, want %+v", raw, got, score)
+		}
+	}
+
+	// 解析失败时三列均为NULL，还原为nil
+	low, high, noEvidence := Columns(Parse("Excellent"))
+	if low != nil || high != nil || noEvidence != nil {
+		t.Errorf("Columns of unparseable label = %v, %v, %v, want NULLs", low, high, noEvidence)
+	}
+	if got := FromColumns(sql.NullFloat64{}, sql.NullFloat64{}, sql.NullBool{}); got != nil {
+		t.Errorf("FromColumns(NULL) = %+v, want nil", got)
+	}
+}
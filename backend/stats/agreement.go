@@ -0,0 +1,344 @@
+package stats
+
+import (
+	"sort"
+)
+
+// Unit 一个被评分条目：评分者 -> 评分
+type Unit map[string]int
+
+// Categories 返回所有条目中出现过的评分，升序排列
+func Categories(units []Unit) []int {
+	seen := map[int]bool{}
+	for _, unit := range units {
+		for _, value := range unit {
+			seen[value] = true
+		}
+	}
+	categories := make([]int, 0, len(seen))
+	for value := range seen {
+		categories = append(categories, value)
+	}
+	sort.Ints(categories)
+	return categories
+}
+
+// Raters 返回所有条目中出现过的评分者，按名称排序
+func Raters(units []Unit) []string {
+	seen := map[string]bool{}
+	for _, unit := range units {
+		for rater := range unit {
+			seen[rater] = true
+		}
+	}
+	raters := make([]string, 0, len(seen))
+	for rater := range seen {
+		raters = append(raters, rater)
+	}
+	sort.Strings(raters)
+	return raters
+}
+
+// PairableUnits 返回至少有两位评分者的条目
+func PairableUnits(units []Unit) []Unit {
+	pairable := []Unit{}
+	for _, unit := range units {
+		if len(unit) >= 2 {
+			pairable = append(pairable, unit)
+		}
+	}
+	return pairable
+}
+
+// PercentAgreement 成对一致率：各条目内评分一致的评分者对所占比例的平均值。
+// 没有可比较条目时ok为false。
+func PercentAgreement(units []Unit) (float64, bool) {
+	units = PairableUnits(units)
+	if len(units) == 0 {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, unit := range units {
+		total += unitAgreement(unit)
+	}
+	return total / float64(len(units)), true
+}
+
+// unitAgreement 单个条目内评分一致的评分者对比例
+func unitAgreement(unit Unit) float64 {
+	counts := map[int]int{}
+	for _, value := range unit {
+		counts[value]++
+	}
+	n := len(unit)
+	agreeing := 0
+	for _, count := range counts {
+		agreeing += count * (count - 1)
+	}
+	return float64(agreeing) / float64(n*(n-1))
+}
+
+// CohenKappa 两位评分者在同一组条目上的Cohen's kappa。
+// a和b按下标配对；期望一致率为1时（双方只用了同一个评分）ok为false。
+func CohenKappa(a, b []int) (float64, bool) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, false
+	}
+
+	n := float64(len(a))
+	countsA := map[int]float64{}
+	countsB := map[int]float64{}
+	agree := 0.0
+	for i := range a {
+		countsA[a[i]]++
+		countsB[b[i]]++
+		if a[i] == b[i] {
+			agree++
+		}
+	}
+
+	observed := agree / n
+	expected := 0.0
+	for value, countA := range countsA {
+		expected += (countA / n) * (countsB[value] / n)
+	}
+	if expected >= 1 {
+		return 0, false
+	}
+	return (observed - expected) / (1 - expected), true
+}
+
+// Weighting 加权kappa中两个评分不一致时的权重
+type Weighting int
+
+const (
+	Unweighted       Weighting = iota // 不一致即为1
+	LinearWeights                     // |a-b|
+	QuadraticWeights                  // (a-b)^2
+)
+
+func (w Weighting) disagreement(a, b int) float64 {
+	diff := float64(a - b)
+	switch w {
+	case LinearWeights:
+		if diff < 0 {
+			diff = -diff
+		}
+		return diff
+	case QuadraticWeights:
+		return diff * diff
+	default:
+		if a == b {
+			return 0
+		}
+		return 1
+	}
+}
+
+// WeightedCohenKappa 按评分差距加权的Cohen's kappa：1 - 观察到的加权不一致 / 期望的加权不一致。
+// 评分为有序整数，权重按评分之差计算；Unweighted时与CohenKappa相同。
+// 期望不一致为0时（双方只用了同一个评分）ok为false。
+func WeightedCohenKappa(a, b []int, weighting Weighting) (float64, bool) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, false
+	}
+
+	n := float64(len(a))
+	countsA := map[int]float64{}
+	countsB := map[int]float64{}
+	observed := 0.0
+	for i := range a {
+		countsA[a[i]]++
+		countsB[b[i]]++
+		observed += weighting.disagreement(a[i], b[i])
+	}
+	observed /= n
+
+	expected := 0.0
+	for valueA, countA := range countsA {
+		for valueB, countB := range countsB {
+			expected += (countA / n) * (countB / n) * weighting.disagreement(valueA, valueB)
+		}
+	}
+	if expected == 0 {
+		return 0, false
+	}
+	return 1 - observed/expected, true
+}
+
+// PairResult 一对评分者之间的一致性
+type PairResult struct {
+	RaterA           string   `json:"rater_a"`
+	RaterB           string   `json:"rater_b"`
+	Units            int      `json:"units"`
+	PercentAgreement *float64 `json:"percent_agreement"`
+	CohenKappa       *float64 `json:"cohen_kappa"`
+	QuadraticKappa   *float64 `json:"quadratic_weighted_kappa"`
+}
+
+// PairwiseCohen 计算所有评分者对在共同条目上的Cohen's kappa（不加权和平方加权）
+func PairwiseCohen(units []Unit) []PairResult {
+	raters := Raters(units)
+	results := []PairResult{}
+	for i := 0; i < len(raters); i++ {
+		for j := i + 1; j < len(raters); j++ {
+			var a, b []int
+			for _, unit := range units {
+				va, okA := unit[raters[i]]
+				vb, okB := unit[raters[j]]
+				if okA && okB {
+					a = append(a, va)
+					b = append(b, vb)
+				}
+			}
+			if len(a) == 0 {
+				continue
+			}
+
+			agree := 0
+			for k := range a {
+				if a[k] == b[k] {
+					agree++
+				}
+			}
+			result := PairResult{
+				RaterA:           raters[i],
+				RaterB:           raters[j],
+				Units:            len(a),
+				PercentAgreement: Float(float64(agree)/float64(len(a)), true),
+			}
+			result.CohenKappa = Float(CohenKappa(a, b))
+			result.QuadraticKappa = Float(WeightedCohenKappa(a, b, QuadraticWeights))
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// MeanPairwiseCohen 各评分者对Cohen's kappa的算术平均
+func MeanPairwiseCohen(pairs []PairResult) (float64, bool) {
+	total, count := 0.0, 0
+	for _, pair := range pairs {
+		if pair.CohenKappa != nil {
+			total += *pair.CohenKappa
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// FleissKappa 多评分者Fleiss' kappa，允许各条目评分人数不同
+// （条目一致率按各自人数计算，类别比例按全部评分计算）
+func FleissKappa(units []Unit) (float64, bool) {
+	units = PairableUnits(units)
+	if len(units) == 0 {
+		return 0, false
+	}
+
+	categoryTotals := map[int]float64{}
+	totalRatings := 0.0
+	observed := 0.0
+	for _, unit := range units {
+		for _, value := range unit {
+			categoryTotals[value]++
+			totalRatings++
+		}
+		observed += unitAgreement(unit)
+	}
+	observed /= float64(len(units))
+
+	expected := 0.0
+	for _, count := range categoryTotals {
+		p := count / totalRatings
+		expected += p * p
+	}
+	if expected >= 1 {
+		return 0, false
+	}
+	return (observed - expected) / (1 - expected), true
+}
+
+// KrippendorffAlphaOrdinal 有序数据的Krippendorff's alpha，允许缺失评分
+func KrippendorffAlphaOrdinal(units []Unit) (float64, bool) {
+	units = PairableUnits(units)
+	categories := Categories(units)
+	if len(categories) == 0 {
+		return 0, false
+	}
+
+	index := map[int]int{}
+	for i, value := range categories {
+		index[value] = i
+	}
+
+	// 构建一致矩阵（coincidence matrix）
+	k := len(categories)
+	coincidence := make([][]float64, k)
+	for i := range coincidence {
+		coincidence[i] = make([]float64, k)
+	}
+	for _, unit := range units {
+		values := make([]int, 0, len(unit))
+		for _, value := range unit {
+			values = append(values, index[value])
+		}
+		weight := 1 / float64(len(values)-1)
+		for i := range values {
+			for j := range values {
+				if i != j {
+					coincidence[values[i]][values[j]] += weight
+				}
+			}
+		}
+	}
+
+	marginals := make([]float64, k)
+	n := 0.0
+	for c := 0; c < k; c++ {
+		for d := 0; d < k; d++ {
+			marginals[c] += coincidence[c][d]
+		}
+		n += marginals[c]
+	}
+	if n <= 1 {
+		return 0, false
+	}
+
+	// 有序距离：delta(c,d) = (sum_{g=c..d} n_g - (n_c + n_d)/2)^2
+	delta := func(c, d int) float64 {
+		if c > d {
+			c, d = d, c
+		}
+		sum := 0.0
+		for g := c; g <= d; g++ {
+			sum += marginals[g]
+		}
+		diff := sum - (marginals[c]+marginals[d])/2
+		return diff * diff
+	}
+
+	observed, expected := 0.0, 0.0
+	for c := 0; c < k; c++ {
+		for d := 0; d < k; d++ {
+			dist := delta(c, d)
+			observed += coincidence[c][d] * dist
+			expected += marginals[c] * marginals[d] * dist
+		}
+	}
+	if expected == 0 {
+		return 0, false
+	}
+	return 1 - (n-1)*observed/expected, true
+}
+
+// Float 将(value, ok)转换为可序列化的指针，未定义时为nil
+func Float(value float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &value
+}
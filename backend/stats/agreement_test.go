@@ -0,0 +1,291 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// repeat 生成评分序列：repeat(0, 46, 1, 44) = 46个0后接44个1
+func repeat(pairs ...int) []int {
+	var values []int
+	for i := 0; i+1 < len(pairs); i += 2 {
+		for j := 0; j < pairs[i+1]; j++ {
+			values = append(values, pairs[i])
+		}
+	}
+	return values
+}
+
+// fromCounts 按每个条目各类别的评分人数生成条目，评分者依次命名为r0, r1, ...
+func fromCounts(categories []int, counts [][]int) []Unit {
+	units := make([]Unit, len(counts))
+	for i, row := range counts {
+		units[i] = Unit{}
+		rater := 0
+		for c, count := range row {
+			for j := 0; j < count; j++ {
+				units[i][fmt.Sprintf("r%d", rater)] = categories[c]
+				rater++
+			}
+		}
+	}
+	return units
+}
+
+// fromColumns 按评分者给出各条目的评分，0表示缺失
+func fromColumns(columns map[string][]int) []Unit {
+	var units []Unit
+	for rater, values := range columns {
+		for len(units) < len(values) {
+			units = append(units, Unit{})
+		}
+		for i, value := range values {
+			if value != 0 {
+				units[i][rater] = value
+			}
+		}
+	}
+	return units
+}
+
+func assertStat(t *testing.T, name string, got float64, gotOK bool, want float64, wantOK bool, tolerance float64) {
+	t.Helper()
+	if gotOK != wantOK {
+		t.Fatalf("%s: ok = %v, want %v (value %.4f)", name, gotOK, wantOK, got)
+	}
+	if wantOK && math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %.6f, want %.6f", name, got, want)
+	}
+}
+
+func TestCohenKappa(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   []int
+		want   float64
+		wantOK bool
+	}{
+		{
+			// Cohen's kappa条目（Wikipedia）：50份申请，两人都同意20、都拒绝15、意见不同15，kappa = 0.4
+			name:   "wikipedia yes/no",
+			a:      repeat(1, 20, 1, 5, 0, 10, 0, 15),
+			b:      repeat(1, 20, 0, 5, 1, 10, 0, 15),
+			want:   0.4,
+			wantOK: true,
+		},
+		{
+			// scikit-learn cohen_kappa_score的参考值
+			name:   "three categories",
+			a:      repeat(0, 46, 1, 44, 2, 10),
+			b:      repeat(0, 52, 1, 32, 2, 16),
+			want:   0.8013,
+			wantOK: true,
+		},
+		{
+			name:   "perfect agreement",
+			a:      []int{1, 2, 3, 4, 5},
+			b:      []int{1, 2, 3, 4, 5},
+			want:   1,
+			wantOK: true,
+		},
+		{
+			// 双方只用了同一个评分，期望一致率为1，kappa无定义
+			name: "all raters agree on one value",
+			a:    []int{3, 3, 3},
+			b:    []int{3, 3, 3},
+		},
+		{name: "length mismatch", a: []int{1, 2}, b: []int{1}},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CohenKappa(tt.a, tt.b)
+			assertStat(t, "CohenKappa", got, ok, tt.want, tt.wantOK, 1e-4)
+
+			// 不加权的WeightedCohenKappa与CohenKappa一致
+			got, ok = WeightedCohenKappa(tt.a, tt.b, Unweighted)
+			assertStat(t, "WeightedCohenKappa(Unweighted)", got, ok, tt.want, tt.wantOK, 1e-4)
+		})
+	}
+}
+
+func TestWeightedCohenKappa(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      []int
+		weighting Weighting
+		want      float64
+		wantOK    bool
+	}{
+		// scikit-learn cohen_kappa_score(weights=None/"linear"/"quadratic")的参考值
+		{"unweighted", repeat(0, 46, 1, 44, 2, 10), repeat(0, 50, 1, 40, 2, 10), Unweighted, 0.9315, true},
+		{"linear", repeat(0, 46, 1, 44, 2, 10), repeat(0, 50, 1, 40, 2, 10), LinearWeights, 0.9412, true},
+		{"quadratic", repeat(0, 46, 1, 44, 2, 10), repeat(0, 50, 1, 40, 2, 10), QuadraticWeights, 0.9541, true},
+		// 只有两个类别时各种权重都相同
+		{"two categories linear", repeat(1, 20, 1, 5, 0, 10, 0, 15), repeat(1, 20, 0, 5, 1, 10, 0, 15), LinearWeights, 0.4, true},
+		{"two categories quadratic", repeat(1, 20, 1, 5, 0, 10, 0, 15), repeat(1, 20, 0, 5, 1, 10, 0, 15), QuadraticWeights, 0.4, true},
+		// 权重只取决于评分之差，与未出现的中间类别无关
+		{"gap in scale", []int{1, 5, 5, 1}, []int{1, 5, 1, 1}, LinearWeights, 0.5, true},
+		{"all raters agree on one value", []int{2, 2}, []int{2, 2}, QuadraticWeights, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := WeightedCohenKappa(tt.a, tt.b, tt.weighting)
+			assertStat(t, "WeightedCohenKappa", got, ok, tt.want, tt.wantOK, 1e-4)
+		})
+	}
+}
+
+// fleissExample Fleiss (1971)的示例（Wikipedia Fleiss' kappa条目）：10个条目，每个14位评分者，5个类别
+var fleissExample = [][]int{
+	{0, 0, 0, 0, 14},
+	{0, 2, 6, 4, 2},
+	{0, 0, 3, 5, 6},
+	{0, 3, 9, 2, 0},
+	{2, 2, 8, 1, 1},
+	{7, 7, 0, 0, 0},
+	{3, 2, 6, 3, 0},
+	{2, 5, 3, 2, 2},
+	{6, 5, 2, 1, 0},
+	{0, 2, 2, 3, 7},
+}
+
+func TestFleissKappa(t *testing.T) {
+	categories := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name   string
+		units  []Unit
+		want   float64
+		wantOK bool
+	}{
+		{"fleiss 1971", fromCounts(categories, fleissExample), 0.2099, true},
+		{
+			// 只有一个评分的条目不参与计算
+			name:   "single-rating units ignored",
+			units:  append(fromCounts(categories, fleissExample), Unit{"r0": 1}, Unit{"r5": 5}),
+			want:   0.2099,
+			wantOK: true,
+		},
+		{
+			name:   "perfect agreement",
+			units:  []Unit{{"a": 1, "b": 1, "c": 1}, {"a": 4, "b": 4, "c": 4}},
+			want:   1,
+			wantOK: true,
+		},
+		{
+			name:  "all raters agree on one value",
+			units: []Unit{{"a": 3, "b": 3}, {"a": 3, "b": 3, "c": 3}},
+		},
+		{name: "only single-rating units", units: []Unit{{"a": 1}, {"b": 2}}},
+		{name: "no units"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FleissKappa(tt.units)
+			assertStat(t, "FleissKappa", got, ok, tt.want, tt.wantOK, 1e-4)
+		})
+	}
+}
+
+// krippendorffExample Krippendorff (2011) "Computing Krippendorff's Alpha-Reliability"的示例：
+// 4位观察者、12个条目，0表示缺失；第12个条目只有一个评分
+var krippendorffExample = map[string][]int{
+	"A": {1, 2, 3, 3, 2, 1, 4, 1, 2, 0, 0, 0},
+	"B": {1, 2, 3, 3, 2, 2, 4, 1, 2, 5, 0, 3},
+	"C": {0, 3, 3, 3, 2, 3, 4, 2, 2, 5, 1, 0},
+	"D": {1, 2, 3, 3, 2, 4, 4, 1, 2, 5, 1, 0},
+}
+
+func TestKrippendorffAlphaOrdinal(t *testing.T) {
+	tests := []struct {
+		name   string
+		units  []Unit
+		want   float64
+		wantOK bool
+	}{
+		// 论文给出的ordinal alpha为0.815
+		{"krippendorff 2011", fromColumns(krippendorffExample), 0.815, true},
+		{
+			name:   "perfect agreement with missing ratings",
+			units:  []Unit{{"a": 1, "b": 1}, {"a": 3, "c": 3}, {"b": 5, "c": 5, "d": 5}},
+			want:   1,
+			wantOK: true,
+		},
+		{
+			// 两个条目评分相反，观察到的不一致大于期望
+			name:   "systematic disagreement",
+			units:  []Unit{{"a": 1, "b": 2}, {"a": 2, "b": 1}},
+			want:   -0.5,
+			wantOK: true,
+		},
+		{
+			name:  "all raters agree on one value",
+			units: []Unit{{"a": 2, "b": 2}, {"a": 2, "b": 2, "c": 2}},
+		},
+		{name: "only single-rating units", units: []Unit{{"a": 1}, {"b": 5}}},
+		{name: "no units"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := KrippendorffAlphaOrdinal(tt.units)
+			assertStat(t, "KrippendorffAlphaOrdinal", got, ok, tt.want, tt.wantOK, 5e-4)
+		})
+	}
+}
+
+func TestPercentAgreement(t *testing.T) {
+	tests := []struct {
+		name   string
+		units  []Unit
+		want   float64
+		wantOK bool
+	}{
+		// 条目1：3对中1对一致；条目2：全部一致；单评分条目忽略
+		{"pairs within units", []Unit{{"a": 1, "b": 1, "c": 2}, {"a": 4, "b": 4}, {"a": 5}}, (1.0/3 + 1) / 2, true},
+		{"only single-rating units", []Unit{{"a": 1}}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := PercentAgreement(tt.units)
+			assertStat(t, "PercentAgreement", got, ok, tt.want, tt.wantOK, 1e-9)
+		})
+	}
+}
+
+func TestPairwiseCohenUsesCommonUnits(t *testing.T) {
+	// b缺失第3个条目；c只评了一个条目且与其他人相同，期望一致率为1，kappa无定义
+	units := []Unit{
+		{"a": 1, "b": 1, "c": 1},
+		{"a": 2, "b": 2},
+		{"a": 3},
+		{"a": 3, "b": 2},
+	}
+	pairs := PairwiseCohen(units)
+	if len(pairs) != 3 {
+		t.Fatalf("got %d pairs, want 3", len(pairs))
+	}
+
+	ab := pairs[0]
+	if ab.RaterA != "a" || ab.RaterB != "b" || ab.Units != 3 {
+		t.Fatalf("first pair = %+v, want a/b on 3 units", ab)
+	}
+	want, _ := CohenKappa([]int{1, 2, 3}, []int{1, 2, 2})
+	if ab.CohenKappa == nil || math.Abs(*ab.CohenKappa-want) > 1e-9 {
+		t.Errorf("a/b kappa = %v, want %v", ab.CohenKappa, want)
+	}
+	if ab.QuadraticKappa == nil {
+		t.Error("a/b quadratic weighted kappa is nil")
+	}
+
+	for _, pair := range pairs[1:] {
+		if pair.Units != 1 || pair.CohenKappa != nil {
+			t.Errorf("pair %s/%s = %+v, want 1 unit and no kappa", pair.RaterA, pair.RaterB, pair)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Median 中位数，空切片时ok为false
+func Median(values []float64) (float64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid], true
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2, true
+}
+
+// MeanAbsoluteError 平均绝对误差
+func MeanAbsoluteError(a, b []float64) (float64, bool) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, false
+	}
+	total := 0.0
+	for i := range a {
+		total += math.Abs(a[i] - b[i])
+	}
+	return total / float64(len(a)), true
+}
+
+// Pearson 皮尔逊相关系数，任一序列方差为0时ok为false
+func Pearson(a, b []float64) (float64, bool) {
+	if len(a) != len(b) || len(a) < 2 {
+		return 0, false
+	}
+	n := float64(len(a))
+	meanA, meanB := 0.0, 0.0
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= n
+	meanB /= n
+
+	cov, varA, varB := 0.0, 0.0, 0.0
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varA*varB), true
+}
+
+// Spearman 斯皮尔曼等级相关系数，并列值取平均秩
+func Spearman(a, b []float64) (float64, bool) {
+	if len(a) != len(b) {
+		return 0, false
+	}
+	return Pearson(ranks(a), ranks(b))
+}
+
+// ranks 计算平均秩（从1开始）
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	result := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			result[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/db"
+	"github.com/phyreview_annotator/models"
+)
+
+// StageFromFlags 根据各阶段完成标记推导当前阶段，阶段必须按顺序完成
+func StageFromFlags(humanCompleted, machineCompleted, reviewCompleted bool) string {
+	switch {
+	case !humanCompleted:
+		return models.StageHumanAnnotation
+	case !machineCompleted:
+		return models.StageMachineEvaluation
+	case !reviewCompleted:
+		return models.StageReviewAndModify
+	default:
+		return models.StageCompleted
+	}
+}
+
+// CurrentStage 计算用户在某医生某任务某trait上所处的工作流阶段。
+// 有进度记录时只看其中的完成标记，与GetTraitProgress一致（重新评价会清除标记而保留旧的评价）；
+// 进度记录缺失时以已保存的人类标注和机器评价为准。
+func CurrentStage(q db.Querier, physicianID, taskID int, evaluator, trait string) (string, error) {
+	var humanCompleted, machineCompleted, reviewCompleted bool
+	err := q.QueryRow(`
+		SELECT
+			CASE WHEN p.id IS NULL THEN EXISTS(
+				SELECT 1 FROM human_annotations
+				WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+			) ELSE COALESCE(p.human_annotation_completed, FALSE) END,
+			CASE WHEN p.id IS NULL THEN EXISTS(
+				SELECT 1 FROM machine_annotation_evaluation
+				WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND trait = $4
+			) ELSE COALESCE(p.machine_evaluation_completed, FALSE) END,
+			COALESCE(p.review_completed, FALSE)
+		FROM (SELECT 1) AS one
+		LEFT JOIN trait_progress p
+		ON p.physician_id = $1 AND p.task_id = $2 AND p.evaluator = $3 AND p.trait = $4
+	`, physicianID, taskID, evaluator, trait).Scan(&humanCompleted, &machineCompleted, &reviewCompleted)
+	if err != nil {
+		return "", err
+	}
+	return StageFromFlags(humanCompleted, machineCompleted, reviewCompleted), nil
+}
+
+// UnlockedTraits 返回用户已完成人类标注、可以查看模型标注的trait。
+// 盲标要求：人类标注完成前不得看到任何模型输出。
+func UnlockedTraits(q db.Querier, physicianID, taskID int, evaluator string) ([]string, error) {
+	var traits []string
+	err := q.QueryRow(`
+		SELECT COALESCE(array_agg(DISTINCT trait), '{}') FROM (
+			SELECT trait FROM trait_progress
+			WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3 AND human_annotation_completed = TRUE
+			UNION
+			SELECT trait FROM human_annotations
+			WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3
+		) AS completed
+	`, physicianID, taskID, evaluator).Scan(pq.Array(&traits))
+	return traits, err
+}
@@ -0,0 +1,121 @@
+package workflow
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/phyreview_annotator/models"
+)
+
+// 任务状态相关错误
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrInvalidTransition = errors.New("invalid task status transition")
+)
+
+// taskTransitions 允许的任务状态转换
+var taskTransitions = map[string][]string{
+	models.TaskStatusPending:    {models.TaskStatusInProgress, models.TaskStatusCancelled},
+	models.TaskStatusInProgress: {models.TaskStatusCompleted, models.TaskStatusCancelled},
+	models.TaskStatusCompleted:  {models.TaskStatusReopened},
+	models.TaskStatusCancelled:  {models.TaskStatusReopened},
+	models.TaskStatusReopened:   {models.TaskStatusInProgress, models.TaskStatusCancelled},
+}
+
+// CanTransition 判断任务能否从from转换到to
+func CanTransition(from, to string) bool {
+	for _, next := range taskTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTaskOpen 任务是否仍接受标注提交
+func IsTaskOpen(status string) bool {
+	switch status {
+	case models.TaskStatusPending, models.TaskStatusInProgress, models.TaskStatusReopened:
+		return true
+	}
+	return false
+}
+
+// lockTaskStatus 在事务中锁定任务行并返回当前状态
+func lockTaskStatus(tx *sql.Tx, taskID, physicianID int) (string, error) {
+	var status string
+	err := tx.QueryRow(`
+		SELECT status FROM tasks WHERE id = $1 AND physician_id = $2 FOR UPDATE
+	`, taskID, physicianID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrTaskNotFound
+	}
+	return status, err
+}
+
+// TransitionTask 校验并执行任务状态转换，同时记录到task_status_history
+func TransitionTask(tx *sql.Tx, taskID, physicianID int, to, changedBy, reason string) error {
+	from, err := lockTaskStatus(tx, taskID, physicianID)
+	if err != nil {
+		return err
+	}
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+	return setTaskStatus(tx, taskID, physicianID, from, to, changedBy, reason)
+}
+
+func setTaskStatus(tx *sql.Tx, taskID, physicianID int, from, to, changedBy, reason string) error {
+	now := time.Now()
+	_, err := tx.Exec(`
+		UPDATE tasks SET status = $1, timestamp = $2 WHERE id = $3 AND physician_id = $4
+	`, to, now, taskID, physicianID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO task_status_history (task_id, physician_id, from_status, to_status, changed_by, reason, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, taskID, physicianID, from, to, changedBy, reason, now)
+	return err
+}
+
+// AdvanceTask 由trait工作流提交触发的自动状态转换：
+// 首次提交时 pending/reopened -> in_progress，
+// 指派人完成全部trait回顾后 in_progress -> completed。
+// 已完成或已取消的任务返回ErrInvalidTransition。
+func AdvanceTask(tx *sql.Tx, taskID, physicianID int, evaluator string) error {
+	status, err := lockTaskStatus(tx, taskID, physicianID)
+	if err != nil {
+		return err
+	}
+	if !IsTaskOpen(status) {
+		return fmt.Errorf("%w: task is %s", ErrInvalidTransition, status)
+	}
+
+	if status != models.TaskStatusInProgress {
+		err = setTaskStatus(tx, taskID, physicianID, status, models.TaskStatusInProgress, evaluator, "annotation started")
+		if err != nil {
+			return err
+		}
+	}
+
+	var completedTraits int
+	err = tx.QueryRow(`
+		SELECT COUNT(DISTINCT trait) FROM trait_progress
+		WHERE physician_id = $1 AND task_id = $2 AND evaluator = $3
+		AND review_completed = true AND trait = ANY($4)
+	`, physicianID, taskID, evaluator, pq.Array(models.AllTraits)).Scan(&completedTraits)
+	if err != nil {
+		return err
+	}
+
+	if completedTraits == len(models.AllTraits) {
+		return setTaskStatus(tx, taskID, physicianID, models.TaskStatusInProgress, models.TaskStatusCompleted, evaluator, "all traits reviewed")
+	}
+	return nil
+}
@@ -2,12 +2,14 @@ import React, { useState, useEffect } from 'react';
 import { Form, Input, Button, Card, Typography, Alert } from 'antd';
 import { useNavigate } from 'react-router-dom';
 import axios from 'axios';
+import { login, getNextTask } from '../services/api';
 
 const { Title } = Typography;
 
 const Login: React.FC = () => {
   const [loading, setLoading] = useState(false);
   const [apiStatus, setApiStatus] = useState<'checking' | 'connected' | 'error'>('checking');
+  const [loginError, setLoginError] = useState<string | null>(null);
   const navigate = useNavigate();
 
   // 检查API连接状态
@@ -15,7 +17,7 @@ const Login: React.FC = () => {
     const checkApiConnection = async () => {
       const API_URL = process.env.REACT_APP_API_URL || 'http://localhost:8080/api';
       try {
-        await axios.get(`${API_URL}/physician/1659371102`);
+        await axios.get(`${API_URL.replace(/\/api$/, '')}/ping`);
         setApiStatus('connected');
       } catch (error) {
         console.error('API connection check failed:', error);
@@ -26,19 +28,31 @@ const Login: React.FC = () => {
     checkApiConnection();
   }, []);
 
-  const onFinish = (values: { username: string; npi: string; task_id: string }) => {
+  const onFinish = async (values: { username: string; password: string; npi?: string; task_id?: string }) => {
     setLoading(true);
-    
-    // Store user information in session storage
-    sessionStorage.setItem('username', values.username);
-    
-    // Delay to simulate network request
-    setTimeout(() => {
-      setLoading(false);
-      
+    setLoginError(null);
+
+    try {
+      const result = await login(values.username, values.password);
+
+      // Store user information and token in session storage
+      sessionStorage.setItem('username', result.user.username);
+      sessionStorage.setItem('token', result.token);
+
+      // Without an explicit NPI and task ID, take the next task from the queue
+      if (!values.npi || !values.task_id) {
+        const next = await getNextTask();
+        navigate(`/task/${next.npi}/${next.task_id}`);
+        return;
+      }
+
       // Navigate to task page
       navigate(`/task/${values.npi}/${values.task_id}`);
-    }, 500);
+    } catch (error: any) {
+      setLoginError(error.response?.data?.error || 'Login failed');
+    } finally {
+      setLoading(false);
+    }
   };
 
   return (
@@ -73,6 +87,16 @@ const Login: React.FC = () => {
           />
         )}
         
+        {loginError && (
+          <Alert
+            message="Login Failed"
+            description={loginError}
+            type="error"
+            showIcon
+            style={{ marginBottom: 16 }}
+          />
+        )}
+
         <Form
           name="login"
           layout="vertical"
@@ -87,26 +111,32 @@ const Login: React.FC = () => {
             <Input placeholder="Enter your username" />
           </Form.Item>
 
+          <Form.Item
+            label="Password"
+            name="password"
+            rules={[{ required: true, message: 'Please enter your password!' }]}
+          >
+            <Input.Password placeholder="Enter your password" />
+          </Form.Item>
+
           <Form.Item
             label="NPI Number"
             name="npi"
             rules={[
-              { required: true, message: 'Please enter NPI number!' },
               { pattern: /^\d+$/, message: 'NPI number must be numeric!' }
             ]}
           >
-            <Input placeholder="Enter physician's NPI number" />
+            <Input placeholder="Leave empty to get your next task" />
           </Form.Item>
 
           <Form.Item
             label="Task ID"
             name="task_id"
             rules={[
-              { required: true, message: 'Please enter task ID!' },
               { pattern: /^\d+$/, message: 'Task ID must be numeric!' }
             ]}
           >
-            <Input placeholder="Enter task ID" />
+            <Input placeholder="Leave empty to get your next task" />
           </Form.Item>
 
           <Form.Item>
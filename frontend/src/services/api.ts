@@ -18,6 +18,16 @@ const api = axios.create({
   },
 });
 
+// 添加请求拦截器，附带登录令牌
+api.interceptors.request.use((config) => {
+  const token = sessionStorage.getItem('token');
+  if (token) {
+    config.headers = config.headers || {};
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
 // 添加响应拦截器用于调试
 api.interceptors.response.use(
   (response) => {
@@ -40,6 +50,29 @@ api.interceptors.response.use(
   }
 );
 
+// 登录并获取令牌
+export const login = async (username: string, password: string): Promise<{
+  token: string;
+  expires_at: string;
+  user: { id: number; username: string };
+}> => {
+  const response = await api.post('/auth/login', { username, password });
+  return response.data;
+};
+
+// 领取下一个待处理任务
+export const getNextTask = async (): Promise<{
+  task_id: number;
+  npi: number;
+  physician_id: number;
+  status: string;
+  lease_expires_at: string;
+  policy: string;
+}> => {
+  const response = await api.get('/me/next-task');
+  return response.data;
+};
+
 // 根据NPI号码获取医生信息
 export const getPhysicianByNPI = async (npi: string): Promise<Physician> => {
   const response = await api.get(`/physician/${npi}`);
@@ -50,6 +83,7 @@ export const getPhysicianByNPI = async (npi: string): Promise<Physician> => {
 export const getPhysicianTask = async (npi: string, taskId: number, username: string): Promise<{
   task: Task;
   model_annotations: ModelAnnotation[];
+  unlocked_traits: TraitType[];
 }> => {
   const response = await api.get(`/physician/${npi}/task/${taskId}?username=${username}`);
   return response.data;